@@ -0,0 +1,45 @@
+package client
+
+import "fmt"
+
+// AddAptRepos creates the configured apt hosted and proxy repos.
+// Nexus has no apt groups.
+func (r *ClientConfig) AddAptRepos(config *NexusConfig) error {
+	var hosted []hostedRepo
+	for _, repoReq := range config.AptRepos.Hosted {
+		if len(repoReq.Distribution) == 0 || len(repoReq.Keypair) == 0 {
+			return fmt.Errorf("apt hosted repo %s needs a distribution and a keypair", repoReq.Name)
+		}
+		hosted = append(hosted, newAptHostedRepo(repoReq))
+	}
+	var proxies []proxyRepo
+	for _, repoReq := range config.AptRepos.Proxy {
+		if len(repoReq.Distribution) == 0 {
+			return fmt.Errorf("apt proxy repo %s needs a distribution", repoReq.Name)
+		}
+		proxies = append(proxies, newAptProxyRepo(repoReq))
+	}
+	return r.addRepos("apt", hosted, proxies, nil)
+}
+
+func newAptHostedRepo(c AptHostedRepo) hostedRepo {
+	repo := newHostedRepo(c.HostedRepo)
+	repo.Apt = &apt{
+		Distribution: c.Distribution,
+	}
+	repo.AptSigning = &aptSigning{
+		Keypair:    c.Keypair,
+		Passphrase: c.Passphrase,
+	}
+	return repo
+}
+
+func newAptProxyRepo(c AptProxyRepo) proxyRepo {
+	repo := newProxyRepo(c.ProxyRepo)
+	flat := c.Flat
+	repo.Apt = &apt{
+		Distribution: c.Distribution,
+		Flat:         &flat,
+	}
+	return repo
+}
@@ -0,0 +1,431 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type softQuota struct {
+	Type string `json:"type"`
+	// In bytes
+	Limit int64 `json:"limit"`
+}
+
+type blobStoreRequest struct {
+	Quota *softQuota `json:"softQuota,omitempty"`
+	Path  string     `json:"path"`
+	Name  string     `json:"name"`
+}
+
+type groupBlobStoreRequest struct {
+	Name       string     `json:"name"`
+	Quota      *softQuota `json:"softQuota,omitempty"`
+	Members    []string   `json:"members"`
+	FillPolicy string     `json:"fillPolicy"`
+}
+
+type s3BlobStoreRequest struct {
+	Name                string                `json:"name"`
+	Quota               *softQuota            `json:"softQuota,omitempty"`
+	BucketConfiguration s3BucketConfiguration `json:"bucketConfiguration"`
+}
+
+type s3BucketConfiguration struct {
+	Bucket                   s3Bucket                    `json:"bucket"`
+	BucketSecurity           *s3BucketSecurity           `json:"bucketSecurity,omitempty"`
+	AdvancedBucketConnection *s3AdvancedBucketConnection `json:"advancedBucketConnection,omitempty"`
+}
+
+type s3Bucket struct {
+	Region     string `json:"region"`
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"`
+	Expiration int    `json:"expiration"`
+}
+
+type s3BucketSecurity struct {
+	AccessKeyId     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	Role            string `json:"role,omitempty"`
+	SessionToken    string `json:"sessionToken,omitempty"`
+}
+
+type s3AdvancedBucketConnection struct {
+	Endpoint       string `json:"endpoint,omitempty"`
+	ForcePathStyle bool   `json:"forcePathStyle"`
+}
+
+// storeType is the type of the blob store in the api path. Defaults to file.
+func (c BlobStore) storeType() string {
+	if len(c.Type) == 0 {
+		return "file"
+	}
+	return strings.ToLower(c.Type)
+}
+
+// Units of the quota limit. Plain numbers are bytes.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// parseSize converts a positive size with unit like 10GiB to bytes.
+func parseSize(size string) (int64, error) {
+	size = strings.TrimSpace(size)
+	number := strings.TrimRightFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(size[len(number):]))]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit in size %s. Use B, KB, MB, GB, TB, KiB, MiB, GiB or TiB", size)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %s", size)
+	}
+	bytes := int64(value * float64(unit))
+	if bytes <= 0 {
+		return 0, fmt.Errorf("size %s must be positive", size)
+	}
+	return bytes, nil
+}
+
+// formatSize renders bytes with the largest unit that divides them, binary units first.
+func formatSize(bytes int64) string {
+	for _, unit := range []string{"TiB", "GiB", "MiB", "KiB", "TB", "GB", "MB", "KB"} {
+		factor := sizeUnits[strings.ToLower(unit)]
+		if bytes >= factor && bytes%factor == 0 {
+			return fmt.Sprintf("%d%s", bytes/factor, unit)
+		}
+	}
+	return fmt.Sprintf("%dB", bytes)
+}
+
+// newSoftQuota returns nil without quota, so existing stores keep theirs.
+func newSoftQuota(c BlobStore) (*softQuota, error) {
+	var limit int64
+	switch {
+	case len(c.Quota) > 0:
+		bytes, err := parseSize(c.Quota)
+		if err != nil {
+			return nil, fmt.Errorf("blob store %s: %s", c.Name, err)
+		}
+		limit = bytes
+	case c.Capacity > 0:
+		limit = int64(c.Capacity) << 20
+	default:
+		return nil, nil
+	}
+	quota := &softQuota{Type: "spaceUsedQuota", Limit: limit}
+	switch c.QuotaType {
+	case "", "spaceUsed", "spaceUsedQuota":
+	case "spaceRemaining", "spaceRemainingQuota":
+		quota.Type = "spaceRemainingQuota"
+	default:
+		return nil, fmt.Errorf("blob store %s has unknown quota type %s. Use spaceUsed or spaceRemaining", c.Name, c.QuotaType)
+	}
+	return quota, nil
+}
+
+func newBlobStoreRequest(c BlobStore, quota *softQuota) blobStoreRequest {
+	storeRequest := blobStoreRequest{
+		Quota: quota,
+		Path:  c.Path,
+		Name:  c.Name,
+	}
+	// Relative to the blob directory of nexus
+	if len(storeRequest.Path) == 0 {
+		storeRequest.Path = fmt.Sprintf("%s/blobs", c.Name)
+	}
+	return storeRequest
+}
+
+func newS3BlobStoreRequest(c BlobStore, quota *softQuota) s3BlobStoreRequest {
+	storeRequest := s3BlobStoreRequest{
+		Name:  c.Name,
+		Quota: quota,
+		BucketConfiguration: s3BucketConfiguration{
+			Bucket: s3Bucket{
+				Region: c.S3.Region,
+				Name:   c.S3.Bucket,
+				Prefix: c.S3.Prefix,
+				// Nexus removes deleted blobs from the bucket after 3 days by default
+				Expiration: 3,
+			},
+		},
+	}
+	if len(storeRequest.BucketConfiguration.Bucket.Region) == 0 {
+		storeRequest.BucketConfiguration.Bucket.Region = "DEFAULT"
+	}
+	if c.S3.Expiration != nil {
+		storeRequest.BucketConfiguration.Bucket.Expiration = *c.S3.Expiration
+	}
+	// Without credentials nexus uses the default credential chain of the aws sdk
+	if len(c.S3.AccessKeyId) > 0 || len(c.S3.Role) > 0 {
+		storeRequest.BucketConfiguration.BucketSecurity = &s3BucketSecurity{
+			AccessKeyId:     c.S3.AccessKeyId,
+			SecretAccessKey: c.S3.SecretAccessKey,
+			Role:            c.S3.Role,
+			SessionToken:    c.S3.SessionToken,
+		}
+	}
+	if len(c.S3.Endpoint) > 0 || c.S3.ForcePathStyle {
+		storeRequest.BucketConfiguration.AdvancedBucketConnection = &s3AdvancedBucketConnection{
+			Endpoint:       c.S3.Endpoint,
+			ForcePathStyle: c.S3.ForcePathStyle,
+		}
+	}
+	return storeRequest
+}
+
+func newGroupBlobStoreRequest(c BlobStore, quota *softQuota) groupBlobStoreRequest {
+	storeRequest := groupBlobStoreRequest{
+		Name:       c.Name,
+		Quota:      quota,
+		Members:    c.Members,
+		FillPolicy: c.FillPolicy,
+	}
+	if len(storeRequest.FillPolicy) == 0 {
+		storeRequest.FillPolicy = "roundRobin"
+	}
+	return storeRequest
+}
+
+// newStoreRequest builds the request for the type of the blob store.
+func newStoreRequest(c BlobStore) (interface{}, error) {
+	quota, err := newSoftQuota(c)
+	if err != nil {
+		return nil, err
+	}
+	switch storeType := c.storeType(); storeType {
+	case "file":
+		return newBlobStoreRequest(c, quota), nil
+	case "s3":
+		if len(c.S3.Bucket) == 0 {
+			return nil, fmt.Errorf("s3 blob store %s needs a bucket", c.Name)
+		}
+		return newS3BlobStoreRequest(c, quota), nil
+	case "group":
+		if len(c.Members) == 0 {
+			return nil, fmt.Errorf("group blob store %s needs members", c.Name)
+		}
+		switch c.FillPolicy {
+		case "", "roundRobin", "writeToFirst":
+		default:
+			return nil, fmt.Errorf("group blob store %s has unknown fill policy %s. Use roundRobin or writeToFirst", c.Name, c.FillPolicy)
+		}
+		return newGroupBlobStoreRequest(c, quota), nil
+	default:
+		return nil, fmt.Errorf("blob store %s has unknown type %s. Use file, s3 or group", c.Name, storeType)
+	}
+}
+
+// AddBlobStores creates or updates the blob stores.
+// Groups come last, because nexus rejects groups with unknown members.
+func (r *ClientConfig) AddBlobStores(stores []BlobStore) error {
+	// Validate all stores before the first write
+	for _, store := range stores {
+		_, err := newStoreRequest(store)
+		if err != nil {
+			return err
+		}
+	}
+	var groups []BlobStore
+	for _, store := range stores {
+		if store.storeType() == "group" {
+			groups = append(groups, store)
+			continue
+		}
+		err := r.AddBlobStore(store)
+		if err != nil {
+			return err
+		}
+	}
+	for _, store := range groups {
+		err := r.AddBlobStore(store)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddBlobStore creates the blob store or updates it if it differs.
+func (r *ClientConfig) AddBlobStore(store BlobStore) error {
+	storeRequest, err := newStoreRequest(store)
+	if err != nil {
+		return err
+	}
+	request, err := r.newRequest("GET", fmt.Sprintf("blobstores/%s/quota-status", store.Name), nil)
+	if err != nil {
+		return err
+	}
+	getResponse, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = getResponse.Body.Close()
+
+	switch status := getResponse.StatusCode; status {
+	case http.StatusOK:
+		logger.Info(fmt.Sprintf("Blobstore %s already defined", store.Name))
+		return r.updateBlobStore(store, storeRequest)
+	case http.StatusNotFound:
+		{
+			r.record("blobstore", store.Name, ActionCreate, nil)
+			if r.DryRun {
+				return nil
+			}
+			logger.Info(fmt.Sprintf("Creating %s blobstore %s ", store.storeType(), store.Name))
+			return r.createBlobStore(store, storeRequest)
+		}
+	default:
+		return NexusError{
+			message:    "Unknown error",
+			statuscode: status,
+		}
+	}
+}
+
+// updateBlobStore updates the blob store if it differs.
+// Settings not in the config, e.g. the quota, keep their live values.
+func (r *ClientConfig) updateBlobStore(store BlobStore, storeRequest interface{}) error {
+	path := fmt.Sprintf("blobstores/%s/%s", store.storeType(), store.Name)
+	var live map[string]interface{}
+	err := r.getJson(path, &live)
+	var nexusError NexusError
+	if errors.As(err, &nexusError) && (nexusError.statuscode == http.StatusNotFound || nexusError.statuscode == http.StatusBadRequest) {
+		return r.recordBlobStoreType(store)
+	}
+	if err != nil {
+		return err
+	}
+	if fileRequest, ok := storeRequest.(blobStoreRequest); ok {
+		// The path of an existing store can't change
+		livePath := fmt.Sprint(live["path"])
+		if len(store.Path) > 0 && store.Path != livePath {
+			logger.Warn(fmt.Sprintf("Blobstore %s keeps its path %s. Nexus can't move it to %s", store.Name, livePath, store.Path))
+		}
+		fileRequest.Path = livePath
+		storeRequest = fileRequest
+	}
+	if groupRequest, ok := storeRequest.(groupBlobStoreRequest); ok {
+		groupRequest.Members = r.mergeMembers(blobStoreMembers(live), groupRequest.Members)
+		storeRequest = groupRequest
+	}
+	desired, err := toMap(storeRequest)
+	if err != nil {
+		return err
+	}
+	// Nexus replaces the whole store, so unset settings are sent with their live values
+	body, err := toMap(live)
+	if err != nil {
+		return err
+	}
+	mergeMaps(body, desired)
+	// Reported by nexus, but not accepted
+	delete(body, "type")
+	delete(desired, "name")
+	delete(desired, "path")
+	changes := r.withSecretChanges(fmt.Sprintf("blobstore %s", store.Name), diffFields("", desired, live), desired)
+	if len(changes) == 0 {
+		r.record("blobstore", store.Name, ActionNoop, nil)
+		return nil
+	}
+	r.record("blobstore", store.Name, ActionUpdate, changes)
+	for _, change := range changes {
+		logger.Info(fmt.Sprintf("Blobstore %s differs in %s", store.Name, change))
+	}
+	if r.DryRun {
+		return nil
+	}
+	request, err := r.newRequest("PUT", path, body)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	switch status := response.StatusCode; status {
+	case http.StatusOK, http.StatusNoContent:
+		logger.Info(fmt.Sprintf("Blobstore %s updated", store.Name))
+	default:
+		return NexusError{
+			message:    "Unknown error",
+			statuscode: status,
+		}
+	}
+	return nil
+}
+
+// recordBlobStoreType records the differing type of the existing store as drift.
+// Nexus can't change the type of a store, so it is only reported.
+func (r *ClientConfig) recordBlobStoreType(store BlobStore) error {
+	var stores []blobStoreStatus
+	err := r.getJson("blobstores", &stores)
+	if err != nil {
+		return err
+	}
+	var liveType interface{}
+	for _, liveStore := range stores {
+		if liveStore.Name == store.Name {
+			liveType = strings.ToLower(liveStore.Type)
+		}
+	}
+	change := FieldChange{Path: "type", Live: liveType, Desired: store.storeType()}
+	r.record("blobstore", store.Name, ActionUpdate, []FieldChange{change})
+	logger.Warn(fmt.Sprintf("Blobstore %s differs in %s. Nexus can't change the type, recreate the store", store.Name, change))
+	return nil
+}
+
+func (r *ClientConfig) createBlobStore(store BlobStore, storeRequest interface{}) error {
+	request, err := r.newRequest("POST", fmt.Sprintf("blobstores/%s", store.storeType()), storeRequest)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	switch status := response.StatusCode; status {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		logger.Info(fmt.Sprintf("Blobstore %s created", store.Name))
+
+	default:
+		return NexusError{
+			message:    "Unknown error",
+			statuscode: status,
+		}
+	}
+	return nil
+}
+
+// blobStoreMembers returns the members of the group blob store.
+func blobStoreMembers(store map[string]interface{}) []string {
+	var members []string
+	if names, ok := store["members"].([]interface{}); ok {
+		for _, name := range names {
+			members = append(members, fmt.Sprint(name))
+		}
+	}
+	return members
+}
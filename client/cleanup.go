@@ -0,0 +1,127 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type cleanupPolicyRequest struct {
+	Name                    string `json:"name"`
+	Notes                   string `json:"notes,omitempty"`
+	Format                  string `json:"format"`
+	CriteriaLastDownloaded  int    `json:"criteriaLastDownloaded,omitempty"`
+	CriteriaLastBlobUpdated int    `json:"criteriaLastBlobUpdated,omitempty"`
+	CriteriaAssetRegex      string `json:"criteriaAssetRegex,omitempty"`
+	CriteriaReleaseType     string `json:"criteriaReleaseType,omitempty"`
+}
+
+func newCleanupPolicyRequest(c CleanupPolicy) cleanupPolicyRequest {
+	return cleanupPolicyRequest{
+		Name:                    c.Name,
+		Notes:                   c.Notes,
+		Format:                  c.Format,
+		CriteriaLastDownloaded:  c.LastDownloaded,
+		CriteriaLastBlobUpdated: c.LastBlobUpdated,
+		CriteriaAssetRegex:      c.Regex,
+		CriteriaReleaseType:     c.ReleaseType,
+	}
+}
+
+// AddCleanupPolicies creates the configured cleanup policies or updates them if they differ.
+// Call it before creating the repos that reference the policies.
+func (r *ClientConfig) AddCleanupPolicies(config *NexusConfig) error {
+	for _, policyReq := range config.CleanupPolicies {
+		if len(policyReq.Name) == 0 || len(policyReq.Format) == 0 {
+			return fmt.Errorf("cleanup policy %q needs a name and a format", policyReq.Name)
+		}
+		err := r.createOrUpdateCleanupPolicy(newCleanupPolicyRequest(policyReq))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ClientConfig) createOrUpdateCleanupPolicy(policy cleanupPolicyRequest) error {
+	path := fmt.Sprintf("cleanup-policies/%s", policy.Name)
+	request, err := r.newRequest("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+
+	switch status := response.StatusCode; status {
+	case http.StatusNotFound:
+		r.record("cleanup-policy", policy.Name, ActionCreate, nil)
+		if r.DryRun {
+			return nil
+		}
+		err := r.sendCleanupPolicy("POST", "cleanup-policies", policy)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Cleanup policy %s created", policy.Name))
+		return nil
+	case http.StatusOK:
+	default:
+		return NexusError{
+			message:    "Unknown error",
+			statuscode: status,
+		}
+	}
+
+	var live map[string]interface{}
+	err = r.getJson(path, &live)
+	if err != nil {
+		return err
+	}
+	desired, err := toMap(policy)
+	if err != nil {
+		return err
+	}
+	changes := diffFields("", desired, live)
+	if len(changes) == 0 {
+		r.record("cleanup-policy", policy.Name, ActionNoop, nil)
+		return nil
+	}
+	r.record("cleanup-policy", policy.Name, ActionUpdate, changes)
+	for _, change := range changes {
+		logger.Info(fmt.Sprintf("Cleanup policy %s differs in %s", policy.Name, change))
+	}
+	if r.DryRun {
+		return nil
+	}
+	err = r.sendCleanupPolicy("PUT", path, policy)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Cleanup policy %s updated", policy.Name))
+	return nil
+}
+
+func (r *ClientConfig) sendCleanupPolicy(method string, path string, policy cleanupPolicyRequest) error {
+	request, err := r.newRequest(method, path, policy)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	switch status := response.StatusCode; status {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return NexusError{
+			message:    fmt.Sprintf("Can't save cleanup policy %s", policy.Name),
+			statuscode: status,
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/wesovilabs/koazee"
@@ -20,6 +21,14 @@ type ClientConfig struct {
 	Password string
 	Scheme   string
 	Client   *http.Client
+	// Only read from nexus and record the plan
+	DryRun bool
+	// Save passwords and tokens on every run. Nexus never returns them, so they can't be compared
+	RotateSecrets bool
+	Prune         Prune
+	plan          Plan
+	// The repositories in nexus before the run. Read once
+	repositories []repositorySummary
 }
 
 type NexusError struct {
@@ -61,6 +70,9 @@ func (r *ClientConfig) WaitForUp() error {
 
 func (r *ClientConfig) ChangeAdmin123Password() error {
 	if len(r.Password) > 0 && r.Password != "admin123" {
+		if r.DryRun {
+			return r.planAdmin123Password()
+		}
 		url := fmt.Sprintf(r.baseUrl() + "security/users/admin/change-password")
 		request, err := http.NewRequest("PUT", url, bytes.NewBuffer([]byte(r.Password)))
 		//request, err := http.Post(url, "text/plain", bytes.NewBuffer([]byte(r.Password)))
@@ -78,9 +90,11 @@ func (r *ClientConfig) ChangeAdmin123Password() error {
 		switch status := response.StatusCode; status {
 		case http.StatusUnauthorized:
 			logger.Info("Password already changed")
+			r.record("password", "admin", ActionNoop, nil)
 		case http.StatusNoContent:
 			{
 				logger.Info("Password changed")
+				r.record("password", "admin", ActionUpdate, nil)
 				return nil
 			}
 		default:
@@ -95,61 +109,27 @@ func (r *ClientConfig) ChangeAdmin123Password() error {
 	return nil
 }
 
-func (r *ClientConfig) AddBlobStore(name string, spaceUsedQuotaMb int) error {
-
-	url := fmt.Sprintf(r.baseUrl() + fmt.Sprintf("blobstores/%s/quota-status", name))
-	request, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-	request.Header.Set("accept", "application/json")
-	request.SetBasicAuth("admin", r.Password)
-	getResponse, err := r.Client.Do(request)
-	if err != nil {
-		return err
-	}
-
-	switch status := getResponse.StatusCode; status {
-	case http.StatusOK:
-		logger.Info(fmt.Sprintf("Blobstore %s already defined", name))
-	case http.StatusNotFound:
-		{
-			logger.Info(fmt.Sprintf("Creating blobstore %s ", name))
-			return r.createBlobStore(name, spaceUsedQuotaMb)
-		}
-	default:
-		return NexusError{
-			message:    "Unknown error",
-			statuscode: status,
-		}
-	}
-
-	return nil
-}
-
-func (r *ClientConfig) createBlobStore(name string, spaceUsedQuotaMb int) error {
-	url := fmt.Sprintf(r.baseUrl() + "blobstores/file")
-	storeRequest := newBlobStoreRequest(name, spaceUsedQuotaMb*1000)
-	b, err := json.Marshal(storeRequest)
-	if err != nil {
-		panic(err)
-	}
-	request, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
+// planAdmin123Password records whether the default password is still active.
+// If so, the password is not changed in dry run mode and the following reads use the default one.
+func (r *ClientConfig) planAdmin123Password() error {
+	request, err := r.newRequest("GET", "security/realms/active", nil)
 	if err != nil {
 		return err
 	}
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("accept", "application/json")
-	request.SetBasicAuth("admin", r.Password)
+	request.SetBasicAuth("admin", "admin123")
 	response, err := r.Client.Do(request)
 	if err != nil {
 		return err
 	}
-
+	defer func() {
+		_ = response.Body.Close()
+	}()
 	switch status := response.StatusCode; status {
-	case http.StatusNoContent:
-		logger.Info(fmt.Sprintf("Blobstore %s created", name))
-
+	case http.StatusUnauthorized:
+		r.record("password", "admin", ActionNoop, nil)
+	case http.StatusOK:
+		r.record("password", "admin", ActionUpdate, nil)
+		r.Password = "admin123"
 	default:
 		return NexusError{
 			message:    "Unknown error",
@@ -201,9 +181,17 @@ func (r *ClientConfig) ActivateRealm(realmsRequest []string) error {
 				break
 			}
 		}
+		if r.Prune.Enabled {
+			realmsToActivate = r.pruneRealms(activeRealms, realmsRequest)
+		}
+		if len(realmsToActivate) == 0 {
+			r.record("realms", "active", ActionNoop, nil)
+		} else {
+			r.record("realms", "active", ActionUpdate, []FieldChange{{Path: "active", Live: activeRealms, Desired: realmsToActivate}})
+		}
 	}
 
-	if len(realmsToActivate) > 0 {
+	if len(realmsToActivate) > 0 && !r.DryRun {
 
 		url := fmt.Sprintf(r.baseUrl() + "security/realms/active")
 		//realm := fmt.Sprintf("[\"%s\"]", name)
@@ -237,467 +225,95 @@ func (r *ClientConfig) ActivateRealm(realmsRequest []string) error {
 	return nil
 }
 
-type softQuota struct {
-	Type  string `json:"type"`
-	Limit int    `json:"limit"`
-}
-type blobStoreRequest struct {
-	Quota *softQuota `json:"softQuota,omitempty"`
-	Path  string     `json:"path"`
-	Name  string     `json:"name"`
-}
-
-func newBlobStoreRequest(name string, space int) blobStoreRequest {
-
-	if space > 0 {
-		return blobStoreRequest{
-			Quota: &softQuota{
-				Type:  "spaceUsedQuota",
-				Limit: space,
-			},
-			Path: fmt.Sprintf("%s/blobs", name),
-			Name: name,
+func (r *ClientConfig) AddDockerRepos(config *NexusConfig, repos []DockerGroup) error {
+	for _, repoReq := range repos {
+		switch strings.ToUpper(repoReq.IndexType) {
+		case "", "HUB", "REGISTRY":
+		case "CUSTOM":
+			if len(repoReq.IndexUrl) == 0 {
+				return fmt.Errorf("docker proxy %s with index type CUSTOM needs an indexUrl", repoReq.Name)
+			}
+		default:
+			return fmt.Errorf("docker proxy %s has unknown index type %s. Use HUB, REGISTRY or CUSTOM", repoReq.Name, repoReq.IndexType)
 		}
 	}
-	return blobStoreRequest{
-		Path: fmt.Sprintf("%s/blobs", name),
-		Name: name,
-	}
-}
-
-func (r *ClientConfig) AddDockerRepos(config *NexusConfig, repos []DockerGroup) error {
-	pushRepo, err := r.getOrCreateDockerLocalRepo(config, false)
+	pushRepo := newDockerLocalRepo(config)
+	_, err := r.createOrUpdateRepo("docker", "hosted", pushRepo.Name, pushRepo)
 	if err != nil {
 		return err
 	}
 	logger.Info(fmt.Sprintf("Repo docker pushRepo is there %s", pushRepo.Name))
 
-	pullRepo, err := r.getOrCreateDockerGroupRepo(config, false)
-	if err != nil {
-		return err
-	}
-	logger.Info(fmt.Sprintf("Repo docker pullRepo is there %s", pullRepo.Name))
-
+	pullRepo := newDockerGroupRepo(newDefaultDockerPullGroup(config, pushRepo.Name, repos))
 	for _, repoReq := range repos {
-		repo, err := r.getOrCreateProxyRepo(false, repoReq)
+		repo := newDockerProxyRepos(repoReq)
+		_, err := r.createOrUpdateRepo("docker", "proxy", repo.Name, repo)
 		if err != nil {
 			return err
 		}
 		logger.Info(fmt.Sprintf("Repo docker pullRepo is there %s", repo.Name))
-
-		members := koazee.StreamOf(pullRepo.Group.MemberNames)
-		contains, _ := members.Contains(repoReq.Name)
-		if !contains {
-			pullRepo.Group.MemberNames = append(pullRepo.Group.MemberNames, repoReq.Name)
-			err := r.updateDockerGroupRepo(pullRepo)
-			if err != nil {
-				return err
-			}
-		}
-
 	}
 
-	logger.Info(fmt.Sprintf("Repo docker pushRepo is there %s", pushRepo.Name))
-	return nil
-}
-
-func (r *ClientConfig) getOrCreateDockerLocalRepo(config *NexusConfig, secondCall bool) (*dockerLocalRepo, error) {
-	var dockerLocalRepo *dockerLocalRepo
-	{ // Determine the active realms
-		url := fmt.Sprintf(r.baseUrl() + "repositories/docker/hosted/dockerlocal")
-		request, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		request.Header.Set("Content-Type", "application/json")
-		request.Header.Set("accept", "application/json")
-		request.SetBasicAuth("admin", r.Password)
-		response, err := r.Client.Do(request)
-		if err != nil {
-			return nil, err
-		}
-		// Close request body anyway
-		defer func() {
-			_ = response.Body.Close()
-		}()
-
-		switch status := response.StatusCode; status {
-		case http.StatusOK:
-			{
-				content, err := io.ReadAll(response.Body)
-				if err != nil {
-					return nil, err
-				}
-				err = json.Unmarshal(content, &dockerLocalRepo)
-				if err != nil {
-					return nil, err
-				}
-			}
-		case http.StatusNotFound:
-			{
-				if secondCall {
-					return nil, NexusError{
-						message:    "´Can't create dockerlocal repo ",
-						statuscode: status,
-					}
-				}
-				url := fmt.Sprintf(r.baseUrl() + "repositories/docker/hosted")
-				dockerLocalRepo := newDockerLocalRepo(config)
-				b, err := json.Marshal(dockerLocalRepo)
-				if err != nil {
-					return nil, err
-				}
-				request, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
-				if err != nil {
-					return nil, err
-				}
-				request.Header.Set("Content-Type", "application/json")
-				request.Header.Set("accept", "application/json")
-				request.SetBasicAuth("admin", r.Password)
-				response, err := r.Client.Do(request)
-				if err != nil {
-					return nil, err
-				}
-				defer func() {
-					_ = response.Body.Close()
-				}()
-				switch status := response.StatusCode; status {
-				case http.StatusCreated:
-					return r.getOrCreateDockerLocalRepo(config, true)
-				default:
-					return nil, NexusError{
-						message:    "Unknown error",
-						statuscode: status,
-					}
-				}
-			}
-		default:
-			return nil, NexusError{
-				message:    "Unknown error",
-				statuscode: status,
-			}
-		}
-
+	_, err = r.createOrUpdateRepo("docker", "group", pullRepo.Name, pullRepo)
+	if err != nil {
+		return err
 	}
-	return dockerLocalRepo, nil
+	logger.Info(fmt.Sprintf("Repo docker pullRepo is there %s", pullRepo.Name))
+	return nil
 }
 
-func (r *ClientConfig) getOrCreateDockerGroupRepo(config *NexusConfig, secondCall bool) (*dockerGroupRepo, error) {
-	var dockerGroup *dockerGroupRepo
-	{
-		url := fmt.Sprintf(r.baseUrl() + "repositories/docker/group/dockerGroup")
-		request, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		request.Header.Set("Content-Type", "application/json")
-		request.Header.Set("accept", "application/json")
-		request.SetBasicAuth("admin", r.Password)
-
-		response, err := r.Client.Do(request)
-		if err != nil {
-			return nil, err
-		}
-		// Close request body anyway
-		defer func() {
-			_ = response.Body.Close()
-		}()
-		switch status := response.StatusCode; status {
-		case http.StatusOK:
-			{ // Docker group repo found
-				content, err := io.ReadAll(response.Body)
-				if err != nil {
-					return nil, err
-				}
-				err = json.Unmarshal(content, &dockerGroup)
-				if err != nil {
-					return nil, err
-				}
-			}
-			// Create docker repo
-		case http.StatusNotFound:
-			{
-				if secondCall {
-					return nil, NexusError{
-						message:    "´Can't create dockerGroup repo ",
-						statuscode: status,
-					}
-				}
-				url := fmt.Sprintf(r.baseUrl() + "repositories/docker/group")
-				dockerGroupRepo := newDockerGroupRepo(config)
-				b, err := json.Marshal(dockerGroupRepo)
-				if err != nil {
-					return nil, err
-				}
-				request, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
-				if err != nil {
-					return nil, err
-				}
-				request.Header.Set("Content-Type", "application/json")
-				request.Header.Set("accept", "application/json")
-				request.SetBasicAuth("admin", r.Password)
-				response, err := r.Client.Do(request)
-				if err != nil {
-					return nil, err
-				}
-				defer func() {
-					_ = response.Body.Close()
-				}()
-				switch status := response.StatusCode; status {
-				case http.StatusCreated:
-					return r.getOrCreateDockerGroupRepo(config, true)
-				default:
-					return nil, NexusError{
-						message:    "Unknown error",
-						statuscode: status,
-					}
-				}
-
-			}
-		default:
-			return nil, NexusError{
-				message:    "Unknown error",
-				statuscode: status,
-			}
-		}
-
-	}
-
-	return dockerGroup, nil
+type docker struct {
+	V1Enabled      bool `json:"v1Enabled"`
+	ForceBasicAuth bool `json:"forceBasicAuth"`
+	HttpPort       int  `json:"httpPort,omitempty"`
+	// Sent as null if unset, so nexus removes the connector
+	HttpsPort *int    `json:"httpsPort"`
+	Subdomain *string `json:"subdomain"`
 }
 
-func (r *ClientConfig) updateDockerGroupRepo(repo *dockerGroupRepo) error {
-	url := fmt.Sprintf(r.baseUrl() + fmt.Sprintf("repositories/docker/group/%s", repo.Name))
-	b, err := json.Marshal(repo)
-	if err != nil {
-		return err
-	}
-	request, err := http.NewRequest("PUT", url, bytes.NewBuffer(b))
-	if err != nil {
-		return err
+// newDocker returns the connectors of a docker repo. Repos without port or subdomain
+// are only reachable through a group.
+func newDocker(httpPort int, c DockerConnector) docker {
+	repo := docker{
+		V1Enabled:      c.V1Enabled,
+		ForceBasicAuth: c.ForceBasicAuth,
+		HttpPort:       httpPort,
 	}
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("accept", "application/json")
-	request.SetBasicAuth("admin", r.Password)
-
-	response, err := r.Client.Do(request)
-	if err != nil {
-		return err
+	if c.HttpsPort > 0 {
+		repo.HttpsPort = &c.HttpsPort
 	}
-	switch status := response.StatusCode; status {
-	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
-		{
-			logger.Info(fmt.Sprintf("Member added to %s", repo.Name))
-		}
-	default:
-		{
-			return NexusError{
-				message:    "Unknown error",
-				statuscode: status,
-			}
-		}
-
+	if len(c.Subdomain) > 0 {
+		repo.Subdomain = &c.Subdomain
 	}
-
-	return nil
+	return repo
 }
+
 func newDockerLocalRepo(config *NexusConfig) dockerLocalRepo {
-	return dockerLocalRepo{
-		Name:   "dockerlocal",
+	repo := dockerLocalRepo{
+		Name:   config.DockerPush.Name,
 		Online: true,
 		Storage: struct {
 			BlobStoreName               string `json:"blobStoreName"`
 			StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
 			WritePolicy                 string `json:"writePolicy"`
 		}{
-			BlobStoreName:               "docker",
+			BlobStoreName:               config.DockerPush.BlobStoreName,
 			StrictContentTypeValidation: false,
-			WritePolicy:                 "allow",
-		},
-		Docker: struct {
-			V1Enabled      bool   `json:"v1Enabled"`
-			ForceBasicAuth bool   `json:"forceBasicAuth"`
-			HttpPort       int    `json:"httpPort"`
-			HttpsPort      int    `json:"httpsPort,omitempty"`
-			Subdomain      string `json:"subdomain,omitempty"`
-		}{
-			V1Enabled:      false,
-			ForceBasicAuth: false,
-			HttpPort:       config.DockerPush.Port,
+			WritePolicy:                 config.DockerPush.WritePolicy,
 		},
+		Docker: newDocker(config.DockerPush.Port, config.DockerPush.DockerConnector),
 	}
-}
-func (r *ClientConfig) getOrCreateProxyRepo(secondCall bool, repo DockerGroup) (*dockerLocalRepo, error) {
-	var dockerLocalRepo *dockerLocalRepo
-	{
-		url := fmt.Sprintf(r.baseUrl() + fmt.Sprintf("repositories/docker/proxy/%s", repo.Name))
-		request, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		request.Header.Set("Content-Type", "application/json")
-		request.Header.Set("accept", "application/json")
-		request.SetBasicAuth("admin", r.Password)
-
-		response, err := r.Client.Do(request)
-		if err != nil {
-			return nil, err
-		}
-		// Close request body anyway
-		defer func() {
-			_ = response.Body.Close()
-		}()
-		switch status := response.StatusCode; status {
-		case http.StatusOK:
-			{ // Docker group repo found
-				content, err := io.ReadAll(response.Body)
-				if err != nil {
-					return nil, err
-				}
-				err = json.Unmarshal(content, &dockerLocalRepo)
-				if err != nil {
-					return nil, err
-				}
-			}
-			// Create docker repo
-		case http.StatusNotFound:
-			{
-				if secondCall {
-					return nil, NexusError{
-						message:    fmt.Sprintf("Can't create repo %s", repo.Name),
-						statuscode: status,
-					}
-				}
-				url := fmt.Sprintf(r.baseUrl() + "repositories/docker/proxy")
-				dockerLocalRepo := newDockerProxyRepos(repo.Name, repo.Url, repo.Username, repo.Password)
-				b, err := json.Marshal(dockerLocalRepo)
-				if err != nil {
-					return nil, err
-				}
-				request, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
-				if err != nil {
-					return nil, err
-				}
-				request.Header.Set("Content-Type", "application/json")
-				request.Header.Set("accept", "application/json")
-				request.SetBasicAuth("admin", r.Password)
-				response, err := r.Client.Do(request)
-				if err != nil {
-					return nil, err
-				}
-				defer func() {
-					_ = response.Body.Close()
-				}()
-				switch status := response.StatusCode; status {
-				case http.StatusCreated:
-					return r.getOrCreateProxyRepo(true, repo)
-				default:
-					return nil, NexusError{
-						message:    "Unknown error",
-						statuscode: status,
-					}
-				}
-
-			}
-		default:
-			return nil, NexusError{
-				message:    "Unknown error",
-				statuscode: status,
-			}
-		}
+	if len(repo.Name) == 0 {
+		repo.Name = "dockerlocal"
 	}
-	return dockerLocalRepo, nil
-}
-
-func (r *ClientConfig) CreateRawRepo(c *NexusConfig) error {
-	rawRepo, err := r.getOrCreateRawRepo(c, false)
-	if err != nil {
-		return err
+	if len(repo.Storage.BlobStoreName) == 0 {
+		repo.Storage.BlobStoreName = "docker"
 	}
-	logger.Info(fmt.Sprintf("Repo raw is there %s", rawRepo.Name))
-
-	return nil
-}
-
-func (r *ClientConfig) getOrCreateRawRepo(c *NexusConfig, secondCall bool) (*rawRepo, error) {
-	var rawRepo *rawRepo
-	{
-		url := fmt.Sprintf(r.baseUrl() + fmt.Sprintf("repositories/raw/hosted/%s", c.RawRepo.Name))
-		request, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		request.Header.Set("Content-Type", "application/json")
-		request.Header.Set("accept", "application/json")
-		request.SetBasicAuth("admin", r.Password)
-
-		response, err := r.Client.Do(request)
-		if err != nil {
-			return nil, err
-		}
-		// Close request body anyway
-		defer func() {
-			_ = response.Body.Close()
-		}()
-		switch status := response.StatusCode; status {
-		case http.StatusOK:
-			{ // Docker group repo found
-				content, err := io.ReadAll(response.Body)
-				if err != nil {
-					return nil, err
-				}
-				err = json.Unmarshal(content, &rawRepo)
-				if err != nil {
-					return nil, err
-				}
-			}
-			// Create docker repo
-		case http.StatusNotFound:
-			{
-				if secondCall {
-					return nil, NexusError{
-						message:    fmt.Sprintf("Can't create repo %s", c.RawRepo.Name),
-						statuscode: status,
-					}
-				}
-				url := fmt.Sprintf(r.baseUrl() + "repositories/raw/hosted")
-				b, err := json.Marshal(c.RawRepo)
-				if err != nil {
-					return nil, err
-				}
-				request, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
-				if err != nil {
-					return nil, err
-				}
-				request.Header.Set("Content-Type", "application/json")
-				request.Header.Set("accept", "application/json")
-				request.SetBasicAuth("admin", r.Password)
-				response, err := r.Client.Do(request)
-				if err != nil {
-					return nil, err
-				}
-				defer func() {
-					_ = response.Body.Close()
-				}()
-				switch status := response.StatusCode; status {
-				case http.StatusCreated:
-					return r.getOrCreateRawRepo(c, true)
-				default:
-					return nil, NexusError{
-						message:    "Unknown error",
-						statuscode: status,
-					}
-				}
-
-			}
-		default:
-			return nil, NexusError{
-				message:    "Unknown error",
-				statuscode: status,
-			}
-		}
+	if len(repo.Storage.WritePolicy) == 0 {
+		repo.Storage.WritePolicy = "allow"
 	}
-	return rawRepo, nil
+	repo.Cleanup = newCleanup(config.DockerPush.CleanupPolicies)
+	return repo
 }
 
 type dockerLocalRepo struct {
@@ -708,72 +324,68 @@ type dockerLocalRepo struct {
 		StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
 		WritePolicy                 string `json:"writePolicy"`
 	} `json:"storage"`
-	Cleanup *struct {
-		PolicyNames []string `json:"policyNames"`
-	} `json:"cleanup"`
-	Component struct {
-		ProprietaryComponents bool `json:"proprietaryComponents"`
-	} `json:"component,omitempty"`
-	Docker struct {
-		V1Enabled      bool   `json:"v1Enabled"`
-		ForceBasicAuth bool   `json:"forceBasicAuth"`
-		HttpPort       int    `json:"httpPort"`
-		HttpsPort      int    `json:"httpsPort,omitempty"`
-		Subdomain      string `json:"subdomain,omitempty"`
-	} `json:"docker"`
+	Cleanup *cleanup `json:"cleanup,omitempty"`
+	// Not in the config, so existing repos keep theirs
+	Component *component `json:"component,omitempty"`
+	Docker    docker     `json:"docker"`
 }
 
-type rawRepo struct {
-	Name    string `json:"name"`
-	Url     string `json:"url"`
-	Online  bool   `json:"online"`
-	Storage struct {
-		BlobStoreName               string `json:"blobStoreName"`
-		StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
-		WritePolicy                 string `json:"writePolicy"`
-	} `json:"storage"`
-	Cleanup   interface{} `json:"cleanup"`
-	Component struct {
-		ProprietaryComponents bool `json:"proprietaryComponents"`
-	} `json:"component"`
-	Raw struct {
-		ContentDisposition string `json:"contentDisposition"`
-	} `json:"raw"`
-	Format string `json:"format"`
-	Type   string `json:"type"`
+// newDefaultDockerPullGroup returns the group of dockerPull.
+// Without members it contains the hosted repo first and then the proxies.
+func newDefaultDockerPullGroup(config *NexusConfig, pushRepoName string, proxies []DockerGroup) DockerPullGroup {
+	pullGroup := config.DockerPull
+	if len(pullGroup.Name) == 0 {
+		pullGroup.Name = "dockergroup"
+	}
+	if len(pullGroup.Members) == 0 {
+		pullGroup.Members = []string{pushRepoName}
+		for _, proxy := range proxies {
+			pullGroup.Members = append(pullGroup.Members, proxy.Name)
+		}
+	}
+	return pullGroup
 }
 
-func newDockerGroupRepo(config *NexusConfig) dockerGroupRepo {
-	return dockerGroupRepo{
-		Name:   "dockergroup",
+func newDockerGroupRepo(c DockerPullGroup) dockerGroupRepo {
+	repo := dockerGroupRepo{
+		Name:   c.Name,
 		Online: true,
 		Storage: struct {
 			BlobStoreName               string `json:"blobStoreName"`
 			StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
 		}{
-			BlobStoreName:               "docker",
+			BlobStoreName:               c.BlobStoreName,
 			StrictContentTypeValidation: true,
 		},
 		Group: struct {
 			MemberNames    []string `json:"memberNames"`
 			WritableMember string   `json:"writableMember"`
 		}{
-			MemberNames: []string{"dockerLocal"},
-		},
-		Docker: struct {
-			V1Enabled      bool   `json:"v1Enabled"`
-			ForceBasicAuth bool   `json:"forceBasicAuth"`
-			HttpPort       int    `json:"httpPort"`
-			HttpsPort      int    `json:"httpsPort,omitempty"`
-			Subdomain      string `json:"subdomain,omitempty"`
-		}{
-			V1Enabled:      false,
-			ForceBasicAuth: false,
-			HttpPort:       config.DockerPull.Port,
-			HttpsPort:      0,
-			Subdomain:      "",
+			MemberNames: c.Members,
 		},
+		Docker: newDocker(c.Port, c.DockerConnector),
+	}
+	if len(repo.Storage.BlobStoreName) == 0 {
+		repo.Storage.BlobStoreName = "docker"
+	}
+	return repo
+}
+
+// AddDockerPullGroups creates or updates the additional docker groups.
+// Call it after all repos, so the groups may contain any docker repo.
+func (r *ClientConfig) AddDockerPullGroups(config *NexusConfig) error {
+	for _, groupReq := range config.DockerPullGroups {
+		if len(groupReq.Name) == 0 || len(groupReq.Members) == 0 {
+			return fmt.Errorf("docker pull group %q needs a name and members", groupReq.Name)
+		}
+		repo := newDockerGroupRepo(groupReq)
+		_, err := r.createOrUpdateRepo("docker", "group", repo.Name, repo)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Repo docker pullRepo is there %s", repo.Name))
 	}
+	return nil
 }
 
 //region raw repository
@@ -790,24 +402,18 @@ type dockerGroupRepo struct {
 		MemberNames    []string `json:"memberNames"`
 		WritableMember string   `json:"writableMember"`
 	} `json:"group"`
-	Docker struct {
-		V1Enabled      bool   `json:"v1Enabled"`
-		ForceBasicAuth bool   `json:"forceBasicAuth"`
-		HttpPort       int    `json:"httpPort"`
-		HttpsPort      int    `json:"httpsPort,omitempty"`
-		Subdomain      string `json:"subdomain,omitempty"`
-	} `json:"docker"`
+	Docker docker `json:"docker"`
 }
 
-func newDockerProxyRepos(name string, url string, username string, password string) dockerProxyRepos {
+func newDockerProxyRepos(c DockerGroup) dockerProxyRepos {
 	repo := dockerProxyRepos{
-		Name:   name,
+		Name:   c.Name,
 		Online: true,
 		Storage: struct {
 			BlobStoreName               string `json:"blobStoreName"`
 			StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
 		}{
-			BlobStoreName:               "docker",
+			BlobStoreName:               c.BlobStoreName,
 			StrictContentTypeValidation: false,
 		},
 		Proxy: struct {
@@ -815,7 +421,7 @@ func newDockerProxyRepos(name string, url string, username string, password stri
 			ContentMaxAge  int    `json:"contentMaxAge"`
 			MetadataMaxAge int    `json:"metadataMaxAge"`
 		}{
-			RemoteUrl:      url,
+			RemoteUrl:      c.Url,
 			ContentMaxAge:  1440,
 			MetadataMaxAge: 1440,
 		},
@@ -824,16 +430,9 @@ func newDockerProxyRepos(name string, url string, username string, password stri
 			TimeToLive int  `json:"timeToLive"`
 		}{Enabled: true, TimeToLive: 1440},
 		HttpClient: struct {
-			Blocked    bool `json:"blocked"`
-			AutoBlock  bool `json:"autoBlock"`
-			Connection *struct {
-				Retries                 int    `json:"retries"`
-				UserAgentSuffix         string `json:"userAgentSuffix"`
-				Timeout                 int    `json:"timeout"`
-				EnableCircularRedirects bool   `json:"enableCircularRedirects"`
-				EnableCookies           bool   `json:"enableCookies"`
-				UseTrustStore           bool   `json:"useTrustStore"`
-			} `json:"connection,omitempty"`
+			Blocked        bool            `json:"blocked"`
+			AutoBlock      bool            `json:"autoBlock"`
+			Connection     *connection     `json:"connection,omitempty"`
 			Authentication *authentication `json:"authentication,omitempty"`
 		}{
 			Blocked:   false,
@@ -841,34 +440,58 @@ func newDockerProxyRepos(name string, url string, username string, password stri
 		},
 
 		DockerProxy: struct {
-			IndexType          string `json:"indexType"`
-			IndexUrl           string `json:"indexUrl,omitempty"`
-			CacheForeignLayers bool   `json:"cacheForeignLayers"`
-		}{},
-		Docker: struct {
-			V1Enabled      bool   `json:"v1Enabled"`
-			ForceBasicAuth bool   `json:"forceBasicAuth"`
-			HttpPort       int    `json:"httpPort,omitempty"`
-			HttpsPort      int    `json:"httpsPort,omitempty"`
-			Subdomain      string `json:"subdomain,omitempty"`
-		}{V1Enabled: false, ForceBasicAuth: false},
-
-		//{V1Enabled: false, ForceBasicAuth: false, HttpPort: 8500, HttpsPort: 8501},
-	}
-
-	repo.DockerProxy.CacheForeignLayers = true
+			IndexType                string   `json:"indexType"`
+			IndexUrl                 string   `json:"indexUrl,omitempty"`
+			CacheForeignLayers       bool     `json:"cacheForeignLayers"`
+			ForeignLayerUrlWhitelist []string `json:"foreignLayerUrlWhitelist,omitempty"`
+		}{
+			IndexType:                strings.ToUpper(c.IndexType),
+			IndexUrl:                 c.IndexUrl,
+			CacheForeignLayers:       true,
+			ForeignLayerUrlWhitelist: c.ForeignLayerUrlWhitelist,
+		},
+		Docker: newDocker(0, c.DockerConnector),
+	}
+
 	repo.NegativeCache.Enabled = true
 	repo.NegativeCache.TimeToLive = 1440 // The default 24h
 	repo.HttpClient.AutoBlock = true
-	if "dockerhub" == name {
-		repo.DockerProxy.IndexType = "HUB"
+	if c.ContentMaxAge != nil {
+		repo.Proxy.ContentMaxAge = *c.ContentMaxAge
+	}
+	if c.MetadataMaxAge != nil {
+		repo.Proxy.MetadataMaxAge = *c.MetadataMaxAge
+	}
+	if c.NegativeCache != nil {
+		repo.NegativeCache.Enabled = c.NegativeCache.Enabled
+		repo.NegativeCache.TimeToLive = c.NegativeCache.TimeToLive
+	}
+	if c.AutoBlock != nil {
+		repo.HttpClient.AutoBlock = *c.AutoBlock
+	}
+	repo.HttpClient.Connection = newConnection(c.Connection)
+	repo.RoutingRuleName = newRoutingRuleName(c.RoutingRule)
+	if len(repo.Storage.BlobStoreName) == 0 {
+		repo.Storage.BlobStoreName = "docker"
+	}
+	if c.CacheForeignLayers != nil {
+		repo.DockerProxy.CacheForeignLayers = *c.CacheForeignLayers
+	}
+	// Without index type only the proxy named dockerhub searches docker hub
+	if len(repo.DockerProxy.IndexType) == 0 {
+		if "dockerhub" == c.Name {
+			repo.DockerProxy.IndexType = "HUB"
+		} else {
+			repo.DockerProxy.IndexType = "REGISTRY"
+		}
+	}
+	if repo.DockerProxy.IndexType == "HUB" && len(repo.DockerProxy.IndexUrl) == 0 {
 		repo.DockerProxy.IndexUrl = "https://index.docker.io"
-	} else {
-		repo.DockerProxy.IndexType = "REGISTRY"
 	}
-	if len(username) > 0 {
-		repo.HttpClient.Authentication = &authentication{Username: username, Password: password, Type: "username"}
+	if len(c.Username) > 0 {
+		repo.HttpClient.Authentication = &authentication{Username: c.Username, Password: c.Password, Type: "username"}
 	}
+	repo.Cleanup = newCleanup(c.CleanupPolicies)
 
 	//marshal, _ := json.Marshal(repo)
 	//fmt.Printf("%+v\n", string(marshal))
@@ -876,11 +499,13 @@ func newDockerProxyRepos(name string, url string, username string, password stri
 }
 
 type authentication struct {
-	Type       string `json:"type"`
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	NtlmHost   string `json:"ntlmHost"`
-	NtlmDomain string `json:"ntlmDomain"`
+	// username, ntlm or bearerToken
+	Type        string `json:"type"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	NtlmHost    string `json:"ntlmHost"`
+	NtlmDomain  string `json:"ntlmDomain"`
+	BearerToken string `json:"bearerToken,omitempty"`
 }
 type dockerProxyRepos struct {
 	Name    string `json:"name"`
@@ -889,10 +514,8 @@ type dockerProxyRepos struct {
 		BlobStoreName               string `json:"blobStoreName"`
 		StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
 	} `json:"storage"`
-	Cleanup *struct {
-		PolicyNames []string `json:"policyNames"`
-	} `json:"cleanup,omitempty"`
-	Proxy struct {
+	Cleanup *cleanup `json:"cleanup,omitempty"`
+	Proxy   struct {
 		RemoteUrl      string `json:"remoteUrl"`
 		ContentMaxAge  int    `json:"contentMaxAge"`
 		MetadataMaxAge int    `json:"metadataMaxAge"`
@@ -902,16 +525,9 @@ type dockerProxyRepos struct {
 		TimeToLive int  `json:"timeToLive"`
 	} `json:"negativeCache"`
 	HttpClient struct {
-		Blocked    bool `json:"blocked"`
-		AutoBlock  bool `json:"autoBlock"`
-		Connection *struct {
-			Retries                 int    `json:"retries"`
-			UserAgentSuffix         string `json:"userAgentSuffix"`
-			Timeout                 int    `json:"timeout"`
-			EnableCircularRedirects bool   `json:"enableCircularRedirects"`
-			EnableCookies           bool   `json:"enableCookies"`
-			UseTrustStore           bool   `json:"useTrustStore"`
-		} `json:"connection,omitempty"`
+		Blocked        bool            `json:"blocked"`
+		AutoBlock      bool            `json:"autoBlock"`
+		Connection     *connection     `json:"connection,omitempty"`
 		Authentication *authentication `json:"authentication,omitempty"`
 	} `json:"httpClient"`
 	RoutingRuleName *string `json:"routingRuleName,omitempty"`
@@ -919,17 +535,12 @@ type dockerProxyRepos struct {
 		PreemptivePullEnabled bool   `json:"preemptivePullEnabled"`
 		AssetPathRegex        string `json:"assetPathRegex"`
 	} `json:"replication,omitempty"`
-	Docker struct {
-		V1Enabled      bool   `json:"v1Enabled"`
-		ForceBasicAuth bool   `json:"forceBasicAuth"`
-		HttpPort       int    `json:"httpPort,omitempty"`
-		HttpsPort      int    `json:"httpsPort,omitempty"`
-		Subdomain      string `json:"subdomain,omitempty"`
-	} `json:"docker,omitempty"`
+	Docker      docker `json:"docker"`
 	DockerProxy struct {
 		//[ HUB, REGISTRY, CUSTOM ]
-		IndexType          string `json:"indexType"`
-		IndexUrl           string `json:"indexUrl,omitempty"`
-		CacheForeignLayers bool   `json:"cacheForeignLayers"`
+		IndexType                string   `json:"indexType"`
+		IndexUrl                 string   `json:"indexUrl,omitempty"`
+		CacheForeignLayers       bool     `json:"cacheForeignLayers"`
+		ForeignLayerUrlWhitelist []string `json:"foreignLayerUrlWhitelist,omitempty"`
 	} `json:"dockerProxy"`
 }
@@ -1,21 +1,29 @@
 package client
 
 type NexusConfig struct {
-	Address    string `json:"address"`
-	Port       int    `json:"port"`
-	Password   string `json:"password"`
-	Scheme     string `json:"scheme"`
-	BlobStores []struct {
-		Name     string `json:"name"`
-		Capacity int    `json:"capacity"`
-	} `json:"blobStores"`
-	DockerGroup []DockerGroup
-	DockerPush  struct {
-		Port int `json:"port"`
+	Address     string        `json:"address"`
+	Port        int           `json:"port"`
+	Password    string        `json:"password"`
+	Scheme      string        `json:"scheme"`
+	BlobStores  []BlobStore   `json:"blobStores"`
+	DockerGroup []DockerGroup `json:"dockerGroup"`
+	// The hosted docker repo
+	DockerPush struct {
+		// Defaults to dockerlocal
+		Name string `json:"name"`
+		Port int    `json:"port"`
+		// Defaults to docker
+		BlobStoreName string `json:"blobStoreName"`
+		// allow, allow_once or deny. Defaults to allow
+		WritePolicy     string   `json:"writePolicy"`
+		CleanupPolicies []string `json:"cleanupPolicies"`
+		DockerConnector `mapstructure:",squash"`
 	} `json:"dockerPush"`
-	DockerPull struct {
-		Port int `json:"port"`
-	} `json:"dockerPull"`
+	// The docker group of the hosted repo and the proxies
+	DockerPull DockerPullGroup `json:"dockerPull"`
+	// Additional docker groups, e.g. with vetted upstreams only
+	DockerPullGroups []DockerPullGroup `json:"dockerPullGroups"`
+	// Deprecated: Use RawRepos. Kept as additional hosted raw repo
 	RawRepo struct {
 		Name    string `json:"name"`
 		Online  bool   `json:"online"`
@@ -24,7 +32,72 @@ type NexusConfig struct {
 			StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
 			WritePolicy                 string `json:"writePolicy"`
 		} `json:"storage"`
-	}
+	} `json:"rawRepo"`
+	RawRepos   RawRepos   `json:"rawRepos"`
+	MavenRepos MavenRepos `json:"mavenRepos"`
+	NpmRepos   NpmRepos   `json:"npmRepos"`
+	PypiRepos  PypiRepos  `json:"pypiRepos"`
+	HelmRepos  HelmRepos  `json:"helmRepos"`
+	GoRepos    GoRepos    `json:"goRepos"`
+	AptRepos   AptRepos   `json:"aptRepos"`
+	YumRepos   YumRepos   `json:"yumRepos"`
+	// Repositories of any format nexus supports
+	Repositories []Repository `json:"repositories"`
+	// Realms to activate. Defaults to DockerToken
+	Realms []string `json:"realms"`
+	Prune  Prune    `json:"prune"`
+	Tasks  []Task   `json:"tasks"`
+	// Created before the repositories referencing them
+	CleanupPolicies []CleanupPolicy `json:"cleanupPolicies"`
+	// Created before the proxies referencing them
+	RoutingRules []RoutingRule `json:"routingRules"`
+}
+
+// Prune removes what is in nexus but not in the config.
+type Prune struct {
+	Enabled bool `json:"enabled"`
+	// Delete blob stores that still contain blobs
+	Force bool `json:"force"`
+	// Names of repositories, group members, blob stores and realms that are never removed
+	Protected []string `json:"protected"`
+}
+
+type BlobStore struct {
+	Name string `json:"name"`
+	// file, s3 or group. Defaults to file
+	Type string `json:"type"`
+	// Deprecated: Use Quota. Soft quota in MiB
+	Capacity int `json:"capacity"`
+	// Soft quota limit with unit, e.g. 10GiB or 500MB
+	Quota string `json:"quota"`
+	// spaceUsed or spaceRemaining. Defaults to spaceUsed
+	QuotaType string `json:"quotaType"`
+	// Path of a file blob store. Relative paths are below the blob directory of nexus. Defaults to <name>/blobs
+	Path string `json:"path"`
+	// Only used by s3 blob stores
+	S3 S3BlobStore `json:"s3"`
+	// Names of the blob stores of a group
+	Members []string `json:"members"`
+	// roundRobin or writeToFirst. Defaults to roundRobin
+	FillPolicy string `json:"fillPolicy"`
+}
+
+// S3BlobStore is the bucket of a s3 blob store.
+// Set the endpoint and forcePathStyle for s3 compatible stores like MinIO.
+type S3BlobStore struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+	// Defaults to DEFAULT
+	Region   string `json:"region"`
+	Endpoint string `json:"endpoint"`
+	// Without credentials nexus uses the default credential chain of the aws sdk
+	AccessKeyId     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	Role            string `json:"role"`
+	SessionToken    string `json:"sessionToken"`
+	// Days until deleted blobs are removed from the bucket. -1 disables it. Defaults to 3
+	Expiration     *int `json:"expiration"`
+	ForcePathStyle bool `json:"forcePathStyle"`
 }
 
 type DockerGroup struct {
@@ -32,4 +105,307 @@ type DockerGroup struct {
 	Url      string `json:"url"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Defaults to docker
+	BlobStoreName   string   `json:"blobStoreName"`
+	CleanupPolicies []string `json:"cleanupPolicies"`
+	DockerConnector `mapstructure:",squash"`
+	// Minutes. Defaults to 1440. -1 caches forever
+	ContentMaxAge  *int `json:"contentMaxAge,omitempty"`
+	MetadataMaxAge *int `json:"metadataMaxAge,omitempty"`
+	// Defaults to enabled for 1440 minutes
+	NegativeCache *NegativeCache `json:"negativeCache,omitempty"`
+	// Block the upstream while it is unreachable. Defaults to true
+	AutoBlock  *bool       `json:"autoBlock,omitempty"`
+	Connection *Connection `json:"connection,omitempty"`
+	// HUB, REGISTRY or CUSTOM. Defaults to HUB for the proxy named dockerhub and REGISTRY otherwise
+	IndexType string `json:"indexType,omitempty"`
+	// Defaults to https://index.docker.io for HUB. Required for CUSTOM
+	IndexUrl string `json:"indexUrl,omitempty"`
+	// Defaults to true
+	CacheForeignLayers *bool `json:"cacheForeignLayers,omitempty"`
+	// Regular expressions of the foreign layer urls to cache
+	ForeignLayerUrlWhitelist []string `json:"foreignLayerUrlWhitelist,omitempty"`
+	// Name of the routing rule
+	RoutingRule string `json:"routingRule,omitempty"`
+}
+
+// Connection tunes the http client of a proxy.
+type Connection struct {
+	Retries *int `json:"retries,omitempty"`
+	// Seconds
+	Timeout                 *int   `json:"timeout,omitempty"`
+	UserAgentSuffix         string `json:"userAgentSuffix"`
+	UseTrustStore           bool   `json:"useTrustStore"`
+	EnableCookies           bool   `json:"enableCookies"`
+	EnableCircularRedirects bool   `json:"enableCircularRedirects"`
+}
+
+// DockerPullGroup is a docker group with its own pull port.
+type DockerPullGroup struct {
+	// Defaults to dockergroup for dockerPull
+	Name string `json:"name"`
+	Port int    `json:"port"`
+	// Defaults to docker
+	BlobStoreName string `json:"blobStoreName"`
+	// Names of the hosted and proxy docker repos.
+	// Defaults to the hosted repo and all proxies for dockerPull
+	Members         []string `json:"members"`
+	DockerConnector `mapstructure:",squash"`
+}
+
+// DockerConnector holds how docker clients reach a docker repo besides the http port.
+type DockerConnector struct {
+	HttpsPort int `json:"httpsPort"`
+	// For a reverse proxy routing subdomains to the repos instead of ports
+	Subdomain      string `json:"subdomain"`
+	ForceBasicAuth bool   `json:"forceBasicAuth"`
+	V1Enabled      bool   `json:"v1Enabled"`
+}
+
+// HostedRepo holds the settings every hosted repository shares.
+type HostedRepo struct {
+	Name                        string `json:"name"`
+	BlobStoreName               string `json:"blobStoreName"`
+	StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
+	WritePolicy                 string `json:"writePolicy"`
+	// Names of the cleanup policies. Unset keeps the ones of existing repos, an empty list removes them
+	CleanupPolicies []string `json:"cleanupPolicies"`
+}
+
+// ProxyRepo holds the settings every proxy repository shares.
+type ProxyRepo struct {
+	Name                        string `json:"name"`
+	Url                         string `json:"url"`
+	Username                    string `json:"username"`
+	Password                    string `json:"password"`
+	BearerToken                 string `json:"bearerToken"`
+	BlobStoreName               string `json:"blobStoreName"`
+	StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
+	// Minutes. Defaults to 1440. -1 caches forever
+	ContentMaxAge  *int           `json:"contentMaxAge,omitempty"`
+	MetadataMaxAge *int           `json:"metadataMaxAge,omitempty"`
+	NegativeCache  *NegativeCache `json:"negativeCache,omitempty"`
+	// Names of the cleanup policies. Unset keeps the ones of existing repos, an empty list removes them
+	CleanupPolicies []string `json:"cleanupPolicies"`
+	// Name of the routing rule
+	RoutingRule string `json:"routingRule,omitempty"`
+}
+
+type NegativeCache struct {
+	Enabled bool `json:"enabled"`
+	// Minutes
+	TimeToLive int `json:"timeToLive"`
+}
+
+// GroupRepo holds the settings every group repository shares.
+// Members are added in the given order.
+type GroupRepo struct {
+	Name          string   `json:"name"`
+	BlobStoreName string   `json:"blobStoreName"`
+	Members       []string `json:"members"`
+}
+
+type MavenHostedRepo struct {
+	HostedRepo `mapstructure:",squash"`
+	// RELEASE, SNAPSHOT or MIXED
+	VersionPolicy string `json:"versionPolicy"`
+	// STRICT or PERMISSIVE
+	LayoutPolicy string `json:"layoutPolicy"`
+}
+
+type MavenProxyRepo struct {
+	ProxyRepo     `mapstructure:",squash"`
+	VersionPolicy string `json:"versionPolicy"`
+	LayoutPolicy  string `json:"layoutPolicy"`
+}
+
+type MavenRepos struct {
+	Hosted []MavenHostedRepo `json:"hosted"`
+	Proxy  []MavenProxyRepo  `json:"proxy"`
+	Group  []GroupRepo       `json:"group"`
+}
+
+type NpmProxyRepo struct {
+	ProxyRepo `mapstructure:",squash"`
+	// Remove versions that are not in the upstream catalog (firewall)
+	RemoveNonCataloged bool `json:"removeNonCataloged"`
+	// Remove versions that are quarantined (firewall)
+	RemoveQuarantined bool `json:"removeQuarantined"`
+}
+
+type NpmRepos struct {
+	Hosted []HostedRepo   `json:"hosted"`
+	Proxy  []NpmProxyRepo `json:"proxy"`
+	Group  []GroupRepo    `json:"group"`
+}
+
+type PypiProxyRepo struct {
+	ProxyRepo `mapstructure:",squash"`
+	// Remove versions that are quarantined (firewall)
+	RemoveQuarantined bool `json:"removeQuarantined"`
+}
+
+type PypiRepos struct {
+	Hosted []HostedRepo    `json:"hosted"`
+	Proxy  []PypiProxyRepo `json:"proxy"`
+	Group  []GroupRepo     `json:"group"`
+}
+
+type HelmRepos struct {
+	Hosted []HostedRepo `json:"hosted"`
+	Proxy  []ProxyRepo  `json:"proxy"`
+}
+
+type GoRepos struct {
+	Proxy []ProxyRepo `json:"proxy"`
+	Group []GroupRepo `json:"group"`
+}
+
+type AptHostedRepo struct {
+	HostedRepo `mapstructure:",squash"`
+	// Required, e.g. bookworm
+	Distribution string `json:"distribution"`
+	// PGP signing key pair (armored private key). Required
+	Keypair    string `json:"keypair"`
+	Passphrase string `json:"passphrase"`
+}
+
+type AptProxyRepo struct {
+	ProxyRepo    `mapstructure:",squash"`
+	Distribution string `json:"distribution"`
+	// Is the upstream a flat repository
+	Flat bool `json:"flat"`
+}
+
+type AptRepos struct {
+	Hosted []AptHostedRepo `json:"hosted"`
+	Proxy  []AptProxyRepo  `json:"proxy"`
+}
+
+type YumHostedRepo struct {
+	HostedRepo `mapstructure:",squash"`
+	// Depth of the folders the repodata is created in
+	RepodataDepth int `json:"repodataDepth"`
+	// STRICT or PERMISSIVE
+	DeployPolicy string `json:"deployPolicy"`
+}
+
+type YumProxyRepo struct {
+	ProxyRepo `mapstructure:",squash"`
+	// PGP signing key pair (armored private key)
+	Keypair    string `json:"keypair"`
+	Passphrase string `json:"passphrase"`
+}
+
+type YumGroupRepo struct {
+	GroupRepo  `mapstructure:",squash"`
+	Keypair    string `json:"keypair"`
+	Passphrase string `json:"passphrase"`
+}
+
+type YumRepos struct {
+	Hosted []YumHostedRepo `json:"hosted"`
+	Proxy  []YumProxyRepo  `json:"proxy"`
+	Group  []YumGroupRepo  `json:"group"`
+}
+
+type RawHostedRepo struct {
+	HostedRepo `mapstructure:",squash"`
+	// ATTACHMENT or INLINE
+	ContentDisposition string `json:"contentDisposition"`
+}
+
+type RawProxyRepo struct {
+	ProxyRepo          `mapstructure:",squash"`
+	ContentDisposition string `json:"contentDisposition"`
+}
+
+type RawGroupRepo struct {
+	GroupRepo          `mapstructure:",squash"`
+	ContentDisposition string `json:"contentDisposition"`
+}
+
+type RawRepos struct {
+	Hosted []RawHostedRepo `json:"hosted"`
+	Proxy  []RawProxyRepo  `json:"proxy"`
+	Group  []RawGroupRepo  `json:"group"`
+}
+
+// Repository declares a repository of any nexus format.
+// The settings of HostedRepo, ProxyRepo and GroupRepo apply depending on Type.
+// Attributes are merged into the nexus request as they are,
+// e.g. {"nugetProxy": {"queryCacheItemMaxAge": 3600, "nugetVersion": "V3"}}.
+type Repository struct {
+	// nuget, rubygems, conan, cargo, conda, r, ...
+	Format string `json:"format"`
+	// hosted, proxy or group
+	Type                        string                 `json:"type"`
+	Name                        string                 `json:"name"`
+	BlobStoreName               string                 `json:"blobStoreName"`
+	StrictContentTypeValidation bool                   `json:"strictContentTypeValidation"`
+	WritePolicy                 string                 `json:"writePolicy,omitempty"`
+	Url                         string                 `json:"url,omitempty"`
+	Username                    string                 `json:"username,omitempty"`
+	Password                    string                 `json:"password,omitempty"`
+	BearerToken                 string                 `json:"bearerToken,omitempty"`
+	ContentMaxAge               *int                   `json:"contentMaxAge,omitempty"`
+	MetadataMaxAge              *int                   `json:"metadataMaxAge,omitempty"`
+	NegativeCache               *NegativeCache         `json:"negativeCache,omitempty"`
+	Members                     []string               `json:"members,omitempty"`
+	CleanupPolicies             []string               `json:"cleanupPolicies,omitempty"`
+	RoutingRule                 string                 `json:"routingRule,omitempty"`
+	Attributes                  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// Task is a scheduled nexus task. Tasks are matched by name.
+type Task struct {
+	Name string `json:"name"`
+	// blobstore.compact, repository.docker.gc, repository.docker.upload-purge,
+	// repository.cleanup, repository.rebuild-index or any other nexus task type
+	Type string `json:"type"`
+	// Defaults to true
+	Enabled    *bool  `json:"enabled,omitempty"`
+	AlertEmail string `json:"alertEmail,omitempty"`
+	// manual, once, hourly, daily, weekly, monthly or cron
+	Schedule string `json:"schedule"`
+	// Quartz cron expression of the cron schedule, e.g. "0 0 2 * * ?"
+	CronExpression string `json:"cronExpression,omitempty"`
+	// Start of the once, hourly, daily, weekly and monthly schedules, e.g. 2024-01-01T02:00:00 or unix seconds
+	StartDate string `json:"startDate,omitempty"`
+	// Offset of start dates without one, e.g. +02:00. Defaults to UTC
+	TimeZoneOffset string `json:"timeZoneOffset,omitempty"`
+	// Days of the week (1 is sunday) or month the weekly and monthly schedules run on
+	RecurringDays []int `json:"recurringDays,omitempty"`
+	// blobstore.compact
+	BlobStoreName string `json:"blobStoreName,omitempty"`
+	// repository.docker.gc and repository.rebuild-index. * means all
+	RepositoryName string `json:"repositoryName,omitempty"`
+	// repository.docker.upload-purge. Hours
+	Age int `json:"age,omitempty"`
+}
+
+// CleanupPolicy removes components matching all of its criteria.
+type CleanupPolicy struct {
+	Name  string `json:"name"`
+	Notes string `json:"notes,omitempty"`
+	// Format the policy applies to, e.g. docker, maven2, npm or * for all
+	Format string `json:"format"`
+	// Days since the last download
+	LastDownloaded int `json:"lastDownloaded,omitempty"`
+	// Days since the last update
+	LastBlobUpdated int `json:"lastBlobUpdated,omitempty"`
+	// Regex the asset paths match
+	Regex string `json:"regex,omitempty"`
+	// RELEASES or PRERELEASES
+	ReleaseType string `json:"releaseType,omitempty"`
+}
+
+// RoutingRule allows or blocks the requests a proxy sends upstream.
+type RoutingRule struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// ALLOW or BLOCK. Defaults to BLOCK
+	Mode string `json:"mode"`
+	// Regular expressions of the request paths
+	Matchers []string `json:"matchers"`
 }
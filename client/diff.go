@@ -0,0 +1,181 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Nexus never returns secrets, so they can't be compared.
+// With RotateSecrets they are saved on every run instead.
+var secretFields = map[string]bool{
+	"password":        true,
+	"bearerToken":     true,
+	"passphrase":      true,
+	"keypair":         true,
+	"secretAccessKey": true,
+	"sessionToken":    true,
+}
+
+// Nexus reports some enums in a different case than it accepts them, so they are compared case-insensitively.
+var enumFields = map[string]bool{
+	"writePolicy":         true,
+	"versionPolicy":       true,
+	"layoutPolicy":        true,
+	"deployPolicy":        true,
+	"contentDisposition":  true,
+	"indexType":           true,
+	"mode":                true,
+	"fillPolicy":          true,
+	"criteriaReleaseType": true,
+	"type":                true,
+}
+
+// FieldChange is a field of a resource that differs between nexus and the config.
+type FieldChange struct {
+	Path    string      `json:"path"`
+	Live    interface{} `json:"live"`
+	Desired interface{} `json:"desired"`
+}
+
+// String renders the change as live -> desired.
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, formatValue(c.Live), formatValue(c.Desired))
+}
+
+func formatValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// diffFields compares the fields set in desired with live.
+// Fields that are nil in desired or unknown to live are not managed and skipped.
+// Enums are compared case-insensitively, all other strings exactly.
+func diffFields(path string, desired interface{}, live interface{}) []FieldChange {
+	var changes []FieldChange
+	switch d := desired.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		l, _ := live.(map[string]interface{})
+		keys := make([]string, 0, len(d))
+		for key := range d {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if secretFields[key] {
+				continue
+			}
+			liveValue, present := l[key]
+			if l != nil && !present {
+				continue
+			}
+			changes = append(changes, diffFields(joinPath(path, key), d[key], liveValue)...)
+		}
+	case string:
+		l, _ := live.(string)
+		keys := strings.Split(path, ".")
+		equal := d == l || enumFields[keys[len(keys)-1]] && strings.EqualFold(d, l)
+		if !equal {
+			changes = append(changes, FieldChange{Path: path, Live: live, Desired: desired})
+		}
+	default:
+		if live == nil && isZero(desired) {
+			return nil
+		}
+		if !reflect.DeepEqual(desired, live) {
+			changes = append(changes, FieldChange{Path: path, Live: live, Desired: desired})
+		}
+	}
+	return changes
+}
+
+// clearedFields reports the fields at paths that desired sets to null but live still has.
+// diffFields skips them, as null usually means unmanaged.
+func clearedFields(paths []string, desired map[string]interface{}, live map[string]interface{}) []FieldChange {
+	var changes []FieldChange
+	for _, path := range paths {
+		desiredValue, present := lookupField(desired, path)
+		if !present || desiredValue != nil {
+			continue
+		}
+		liveValue, _ := lookupField(live, path)
+		if liveValue != nil {
+			changes = append(changes, FieldChange{Path: path, Live: liveValue, Desired: nil})
+		}
+	}
+	return changes
+}
+
+// lookupField returns the value at the dotted path of m.
+func lookupField(m map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		m, _ = m[key].(map[string]interface{})
+	}
+	value, present := m[keys[len(keys)-1]]
+	return value, present
+}
+
+func joinPath(path string, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+func isZero(v interface{}) bool {
+	switch value := v.(type) {
+	case bool:
+		return !value
+	case float64:
+		return value == 0
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return v == nil
+}
+
+// withSecretChanges adds the secrets set in desired to changes if secrets are rotated.
+// Otherwise it logs that they are not compared.
+func (r *ClientConfig) withSecretChanges(resource string, changes []FieldChange, desired map[string]interface{}) []FieldChange {
+	secrets := secretChanges("", desired)
+	if len(secrets) == 0 {
+		return changes
+	}
+	if !r.RotateSecrets {
+		logger.Info(fmt.Sprintf("Secrets of %s are not compared. Use -rotate-secrets to save them", resource))
+		return changes
+	}
+	return append(changes, secrets...)
+}
+
+// secretChanges returns the secrets set in desired with masked values.
+func secretChanges(path string, desired interface{}) []FieldChange {
+	var changes []FieldChange
+	d, ok := desired.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(d))
+	for key := range d {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value, ok := d[key].(string); ok && secretFields[key] && len(value) > 0 {
+			changes = append(changes, FieldChange{Path: joinPath(path, key), Live: "***", Desired: "***"})
+			continue
+		}
+		changes = append(changes, secretChanges(joinPath(path, key), d[key])...)
+	}
+	return changes
+}
@@ -0,0 +1,477 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wesovilabs/koazee"
+)
+
+type repositorySummary struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Type   string `json:"type"`
+}
+
+// Top level fields of a repository that Repository knows without attributes.
+var repositoryFields = map[string]bool{
+	"name":            true,
+	"format":          true,
+	"type":            true,
+	"url":             true,
+	"cleanup":         true,
+	"online":          true,
+	"storage":         true,
+	"proxy":           true,
+	"negativeCache":   true,
+	"group":           true,
+	"routingRuleName": true,
+}
+
+// Export reads the blob stores, active realms, routing rules and repositories of nexus into a config.
+// Docker and raw repos go to their own sections, all other formats to the generic repositories.
+// Nexus never returns secrets, so passwords of upstreams must be added to the config afterwards.
+// The admin password is left out as well.
+func (r *ClientConfig) Export() (*NexusConfig, error) {
+	config := &NexusConfig{
+		Address: r.Address,
+		Port:    r.Port,
+		Scheme:  r.Scheme,
+	}
+	logger.Warn("Add the admin password to the config")
+
+	var stores []struct {
+		Name      string     `json:"name"`
+		Type      string     `json:"type"`
+		SoftQuota *softQuota `json:"softQuota"`
+	}
+	err := r.getJson("blobstores", &stores)
+	if err != nil {
+		return nil, err
+	}
+	for _, store := range stores {
+		blobStore := BlobStore{Name: store.Name}
+		if store.SoftQuota != nil {
+			blobStore.Quota = formatSize(store.SoftQuota.Limit)
+			blobStore.QuotaType = strings.TrimSuffix(store.SoftQuota.Type, "Quota")
+		}
+		if strings.EqualFold(store.Type, "file") {
+			var file blobStoreRequest
+			err := r.getJson(fmt.Sprintf("blobstores/file/%s", store.Name), &file)
+			if err != nil {
+				return nil, err
+			}
+			if file.Path != fmt.Sprintf("%s/blobs", store.Name) {
+				blobStore.Path = file.Path
+			}
+		}
+		if strings.EqualFold(store.Type, "s3") {
+			err := r.exportS3BlobStore(&blobStore)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if strings.EqualFold(store.Type, "group") {
+			var group groupBlobStoreRequest
+			err := r.getJson(fmt.Sprintf("blobstores/group/%s", store.Name), &group)
+			if err != nil {
+				return nil, err
+			}
+			blobStore.Type = "group"
+			blobStore.Members = group.Members
+			blobStore.FillPolicy = group.FillPolicy
+		}
+		config.BlobStores = append(config.BlobStores, blobStore)
+	}
+
+	err = r.getJson("security/realms/active", &config.Realms)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []routingRuleRequest
+	err = r.getJson("routing-rules", &rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		config.RoutingRules = append(config.RoutingRules, RoutingRule{
+			Name:        rule.Name,
+			Description: rule.Description,
+			Mode:        rule.Mode,
+			Matchers:    rule.Matchers,
+		})
+	}
+
+	var repos []repositorySummary
+	err = r.getJson("repositories", &repos)
+	if err != nil {
+		return nil, err
+	}
+	var dockerRepos []map[string]interface{}
+	for _, summary := range repos {
+		format := apiFormat(summary.Format)
+		live, err := r.getRepo(format, summary.Type, summary.Name)
+		if err != nil {
+			return nil, err
+		}
+		if live == nil {
+			continue
+		}
+		if httpClient, ok := live["httpClient"].(map[string]interface{}); ok && httpClient["authentication"] != nil {
+			logger.Warn(fmt.Sprintf("Add the upstream password of %s to the config", summary.Name))
+		}
+		switch format {
+		case "docker":
+			live["type"] = summary.Type
+			dockerRepos = append(dockerRepos, live)
+		case "raw":
+			err = exportRawRepo(config, summary.Type, live)
+		default:
+			err = exportRepository(config, format, summary.Type, live)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = exportDockerRepos(config, dockerRepos)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// apiFormat maps the format nexus reports to the one of the rest api path.
+func apiFormat(format string) string {
+	if format == "maven2" {
+		return "maven"
+	}
+	return format
+}
+
+// exportDockerRepos maps the first hosted docker repo to dockerPush and the first group
+// containing it to dockerPull, so applying the config doesn't add the default ones.
+func exportDockerRepos(config *NexusConfig, repos []map[string]interface{}) error {
+	var pushName, pullName string
+	for _, live := range repos {
+		if live["type"] == "hosted" {
+			pushName = fmt.Sprint(live["name"])
+			break
+		}
+	}
+	for _, live := range repos {
+		if live["type"] != "group" || len(pushName) == 0 {
+			continue
+		}
+		contains, _ := koazee.StreamOf(groupMembers(live)).Contains(pushName)
+		if contains {
+			pullName = fmt.Sprint(live["name"])
+			break
+		}
+	}
+	for _, live := range repos {
+		err := exportDockerRepo(config, fmt.Sprint(live["type"]), live, pushName, pullName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportDockerRepo(config *NexusConfig, repoType string, live map[string]interface{}, pushName string, pullName string) error {
+	switch repoType {
+	case "hosted":
+		var repo dockerLocalRepo
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		if repo.Name == pushName {
+			config.DockerPush.Name = repo.Name
+			config.DockerPush.Port = repo.Docker.HttpPort
+			config.DockerPush.BlobStoreName = repo.Storage.BlobStoreName
+			config.DockerPush.WritePolicy = strings.ToLower(repo.Storage.WritePolicy)
+			config.DockerPush.DockerConnector = exportDockerConnector(repo.Docker)
+			config.DockerPush.CleanupPolicies = exportCleanup(repo.Cleanup)
+			return nil
+		}
+	case "group":
+		var repo dockerGroupRepo
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		pullGroup := DockerPullGroup{
+			Name:            repo.Name,
+			Port:            repo.Docker.HttpPort,
+			BlobStoreName:   repo.Storage.BlobStoreName,
+			Members:         repo.Group.MemberNames,
+			DockerConnector: exportDockerConnector(repo.Docker),
+		}
+		if repo.Name == pullName {
+			config.DockerPull = pullGroup
+			return nil
+		}
+		config.DockerPullGroups = append(config.DockerPullGroups, pullGroup)
+		return nil
+	case "proxy":
+		var repo dockerProxyRepos
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		dockerGroup := DockerGroup{
+			Name:            repo.Name,
+			Url:             repo.Proxy.RemoteUrl,
+			BlobStoreName:   repo.Storage.BlobStoreName,
+			CleanupPolicies: exportCleanup(repo.Cleanup),
+			DockerConnector: exportDockerConnector(repo.Docker),
+			RoutingRule:     exportRoutingRuleName(repo.RoutingRuleName),
+		}
+		if repo.HttpClient.Authentication != nil {
+			dockerGroup.Username = repo.HttpClient.Authentication.Username
+		}
+		contentMaxAge := repo.Proxy.ContentMaxAge
+		metadataMaxAge := repo.Proxy.MetadataMaxAge
+		autoBlock := repo.HttpClient.AutoBlock
+		dockerGroup.ContentMaxAge = &contentMaxAge
+		dockerGroup.MetadataMaxAge = &metadataMaxAge
+		dockerGroup.NegativeCache = &NegativeCache{
+			Enabled:    repo.NegativeCache.Enabled,
+			TimeToLive: repo.NegativeCache.TimeToLive,
+		}
+		dockerGroup.AutoBlock = &autoBlock
+		cacheForeignLayers := repo.DockerProxy.CacheForeignLayers
+		dockerGroup.IndexType = repo.DockerProxy.IndexType
+		dockerGroup.IndexUrl = repo.DockerProxy.IndexUrl
+		dockerGroup.CacheForeignLayers = &cacheForeignLayers
+		dockerGroup.ForeignLayerUrlWhitelist = repo.DockerProxy.ForeignLayerUrlWhitelist
+		if c := repo.HttpClient.Connection; c != nil && *c != (connection{}) {
+			dockerGroup.Connection = &Connection{
+				Retries:                 c.Retries,
+				Timeout:                 c.Timeout,
+				UserAgentSuffix:         c.UserAgentSuffix,
+				UseTrustStore:           c.UseTrustStore,
+				EnableCookies:           c.EnableCookies,
+				EnableCircularRedirects: c.EnableCircularRedirects,
+			}
+		}
+		config.DockerGroup = append(config.DockerGroup, dockerGroup)
+		return nil
+	}
+	// Docker repos the docker section can't describe
+	return exportRepository(config, "docker", repoType, live)
+}
+
+func exportDockerConnector(d docker) DockerConnector {
+	connector := DockerConnector{
+		ForceBasicAuth: d.ForceBasicAuth,
+		V1Enabled:      d.V1Enabled,
+	}
+	if d.HttpsPort != nil {
+		connector.HttpsPort = *d.HttpsPort
+	}
+	if d.Subdomain != nil {
+		connector.Subdomain = *d.Subdomain
+	}
+	return connector
+}
+
+func exportRawRepo(config *NexusConfig, repoType string, live map[string]interface{}) error {
+	switch repoType {
+	case "hosted":
+		var repo hostedRepo
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		rawRepo := RawHostedRepo{HostedRepo: exportHostedRepo(repo)}
+		if repo.Raw != nil {
+			rawRepo.ContentDisposition = repo.Raw.ContentDisposition
+		}
+		config.RawRepos.Hosted = append(config.RawRepos.Hosted, rawRepo)
+	case "proxy":
+		var repo proxyRepo
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		rawRepo := RawProxyRepo{ProxyRepo: exportProxyRepo(repo)}
+		if repo.Raw != nil {
+			rawRepo.ContentDisposition = repo.Raw.ContentDisposition
+		}
+		config.RawRepos.Proxy = append(config.RawRepos.Proxy, rawRepo)
+	case "group":
+		var repo groupRepo
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		rawRepo := RawGroupRepo{GroupRepo: exportGroupRepo(repo)}
+		if repo.Raw != nil {
+			rawRepo.ContentDisposition = repo.Raw.ContentDisposition
+		}
+		config.RawRepos.Group = append(config.RawRepos.Group, rawRepo)
+	}
+	return nil
+}
+
+// exportRepository adds live as generic repository.
+// Everything Repository has no field for is kept as attribute.
+func exportRepository(config *NexusConfig, format string, repoType string, live map[string]interface{}) error {
+	repository := Repository{Format: format, Type: repoType}
+	switch repoType {
+	case "hosted":
+		var repo hostedRepo
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		hosted := exportHostedRepo(repo)
+		repository.Name = hosted.Name
+		repository.BlobStoreName = hosted.BlobStoreName
+		repository.StrictContentTypeValidation = hosted.StrictContentTypeValidation
+		repository.WritePolicy = hosted.WritePolicy
+		repository.CleanupPolicies = hosted.CleanupPolicies
+	case "proxy":
+		var repo proxyRepo
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		proxy := exportProxyRepo(repo)
+		repository.Name = proxy.Name
+		repository.BlobStoreName = proxy.BlobStoreName
+		repository.StrictContentTypeValidation = proxy.StrictContentTypeValidation
+		repository.Url = proxy.Url
+		repository.Username = proxy.Username
+		repository.ContentMaxAge = proxy.ContentMaxAge
+		repository.MetadataMaxAge = proxy.MetadataMaxAge
+		repository.NegativeCache = proxy.NegativeCache
+		repository.CleanupPolicies = proxy.CleanupPolicies
+		repository.RoutingRule = proxy.RoutingRule
+	case "group":
+		var repo groupRepo
+		err := fromMap(live, &repo)
+		if err != nil {
+			return err
+		}
+		group := exportGroupRepo(repo)
+		repository.Name = group.Name
+		repository.BlobStoreName = group.BlobStoreName
+		repository.Members = group.Members
+	}
+
+	for key, value := range live {
+		if repositoryFields[key] || value == nil {
+			continue
+		}
+		if key == "httpClient" {
+			// The authentication is part of the repository already
+			if httpClient, ok := value.(map[string]interface{}); ok {
+				delete(httpClient, "authentication")
+			}
+		}
+		if repository.Attributes == nil {
+			repository.Attributes = map[string]interface{}{}
+		}
+		repository.Attributes[key] = value
+	}
+	config.Repositories = append(config.Repositories, repository)
+	return nil
+}
+
+func exportHostedRepo(repo hostedRepo) HostedRepo {
+	return HostedRepo{
+		Name:                        repo.Name,
+		BlobStoreName:               repo.Storage.BlobStoreName,
+		StrictContentTypeValidation: repo.Storage.StrictContentTypeValidation,
+		WritePolicy:                 strings.ToLower(repo.Storage.WritePolicy),
+		CleanupPolicies:             exportCleanup(repo.Cleanup),
+	}
+}
+
+func exportCleanup(c *cleanup) []string {
+	if c == nil {
+		return nil
+	}
+	return c.PolicyNames
+}
+
+func exportRoutingRuleName(name *string) string {
+	if name == nil {
+		return ""
+	}
+	return *name
+}
+
+func exportProxyRepo(repo proxyRepo) ProxyRepo {
+	contentMaxAge := repo.Proxy.ContentMaxAge
+	metadataMaxAge := repo.Proxy.MetadataMaxAge
+	proxy := ProxyRepo{
+		Name:                        repo.Name,
+		Url:                         repo.Proxy.RemoteUrl,
+		BlobStoreName:               repo.Storage.BlobStoreName,
+		StrictContentTypeValidation: repo.Storage.StrictContentTypeValidation,
+		ContentMaxAge:               &contentMaxAge,
+		MetadataMaxAge:              &metadataMaxAge,
+		NegativeCache: &NegativeCache{
+			Enabled:    repo.NegativeCache.Enabled,
+			TimeToLive: repo.NegativeCache.TimeToLive,
+		},
+		CleanupPolicies: exportCleanup(repo.Cleanup),
+		RoutingRule:     exportRoutingRuleName(repo.RoutingRuleName),
+	}
+	if repo.HttpClient.Authentication != nil {
+		proxy.Username = repo.HttpClient.Authentication.Username
+	}
+	return proxy
+}
+
+func exportGroupRepo(repo groupRepo) GroupRepo {
+	return GroupRepo{
+		Name:          repo.Name,
+		BlobStoreName: repo.Storage.BlobStoreName,
+		Members:       repo.Group.MemberNames,
+	}
+}
+
+// fromMap converts the generic json representation m to out.
+func fromMap(m map[string]interface{}, out interface{}) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// exportS3BlobStore reads the bucket of the s3 blob store.
+func (r *ClientConfig) exportS3BlobStore(blobStore *BlobStore) error {
+	var store s3BlobStoreRequest
+	err := r.getJson(fmt.Sprintf("blobstores/s3/%s", blobStore.Name), &store)
+	if err != nil {
+		return err
+	}
+	bucket := store.BucketConfiguration.Bucket
+	expiration := bucket.Expiration
+	blobStore.Type = "s3"
+	blobStore.S3 = S3BlobStore{
+		Bucket:     bucket.Name,
+		Prefix:     bucket.Prefix,
+		Region:     bucket.Region,
+		Expiration: &expiration,
+	}
+	if security := store.BucketConfiguration.BucketSecurity; security != nil {
+		blobStore.S3.AccessKeyId = security.AccessKeyId
+		blobStore.S3.Role = security.Role
+		if len(security.AccessKeyId) > 0 {
+			logger.Warn(fmt.Sprintf("Add the secret access key of blobstore %s to the config", blobStore.Name))
+		}
+	}
+	if connection := store.BucketConfiguration.AdvancedBucketConnection; connection != nil {
+		blobStore.S3.Endpoint = connection.Endpoint
+		blobStore.S3.ForcePathStyle = connection.ForcePathStyle
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AddRepositories creates the configured repositories of any format.
+// Hosted and proxy repos are created before the groups.
+func (r *ClientConfig) AddRepositories(config *NexusConfig) error {
+	for _, repoReq := range config.Repositories {
+		if len(repoReq.Format) == 0 || len(repoReq.Name) == 0 {
+			return fmt.Errorf("repo %q needs a name and a format", repoReq.Name)
+		}
+		switch repoReq.Type {
+		case "hosted", "proxy", "group":
+		default:
+			return fmt.Errorf("repo %s has unknown type %q. Use hosted, proxy or group", repoReq.Name, repoReq.Type)
+		}
+	}
+	for _, repoType := range []string{"hosted", "proxy", "group"} {
+		for _, repoReq := range config.Repositories {
+			if repoReq.Type != repoType {
+				continue
+			}
+			err := r.addRepository(repoReq)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *ClientConfig) addRepository(c Repository) error {
+	payload, err := newRepository(c)
+	if err != nil {
+		return err
+	}
+	_, err = r.createOrUpdateRepo(c.Format, c.Type, c.Name, payload)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Repo %s %s is there %s", c.Format, c.Type, c.Name))
+	return nil
+}
+
+// newRepository builds the nexus request for c from the common settings
+// and merges the format specific attributes into it.
+func newRepository(c Repository) (map[string]interface{}, error) {
+	var repo interface{}
+	switch c.Type {
+	case "hosted":
+		repo = newHostedRepo(HostedRepo{
+			Name:                        c.Name,
+			BlobStoreName:               c.BlobStoreName,
+			StrictContentTypeValidation: c.StrictContentTypeValidation,
+			WritePolicy:                 c.WritePolicy,
+			CleanupPolicies:             c.CleanupPolicies,
+		})
+	case "proxy":
+		repo = newProxyRepo(ProxyRepo{
+			Name:                        c.Name,
+			Url:                         c.Url,
+			Username:                    c.Username,
+			Password:                    c.Password,
+			BearerToken:                 c.BearerToken,
+			BlobStoreName:               c.BlobStoreName,
+			StrictContentTypeValidation: c.StrictContentTypeValidation,
+			ContentMaxAge:               c.ContentMaxAge,
+			MetadataMaxAge:              c.MetadataMaxAge,
+			NegativeCache:               c.NegativeCache,
+			CleanupPolicies:             c.CleanupPolicies,
+			RoutingRule:                 c.RoutingRule,
+		})
+	case "group":
+		repo = newGroupRepo(GroupRepo{
+			Name:          c.Name,
+			BlobStoreName: c.BlobStoreName,
+			Members:       c.Members,
+		})
+	default:
+		return nil, fmt.Errorf("repo %s has unknown type %q. Use hosted, proxy or group", c.Name, c.Type)
+	}
+	payload, err := toMap(repo)
+	if err != nil {
+		return nil, err
+	}
+	mergeMaps(payload, c.Attributes)
+	return payload, nil
+}
+
+// toMap converts v to its generic json representation.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+// mergeMaps merges src into dst. Nested maps are merged, all other values replaced.
+func mergeMaps(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
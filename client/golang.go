@@ -0,0 +1,15 @@
+package client
+
+// AddGoRepos creates the configured go module proxy and group repos.
+// Nexus has no hosted go repos.
+func (r *ClientConfig) AddGoRepos(config *NexusConfig) error {
+	var proxies []proxyRepo
+	for _, repoReq := range config.GoRepos.Proxy {
+		proxies = append(proxies, newProxyRepo(repoReq))
+	}
+	var groups []groupRepo
+	for _, repoReq := range config.GoRepos.Group {
+		groups = append(groups, newGroupRepo(repoReq))
+	}
+	return r.addRepos("go", nil, proxies, groups)
+}
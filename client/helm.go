@@ -0,0 +1,15 @@
+package client
+
+// AddHelmRepos creates the configured helm hosted and proxy repos.
+// Nexus has no helm groups.
+func (r *ClientConfig) AddHelmRepos(config *NexusConfig) error {
+	var hosted []hostedRepo
+	for _, repoReq := range config.HelmRepos.Hosted {
+		hosted = append(hosted, newHostedRepo(repoReq))
+	}
+	var proxies []proxyRepo
+	for _, repoReq := range config.HelmRepos.Proxy {
+		proxies = append(proxies, newProxyRepo(repoReq))
+	}
+	return r.addRepos("helm", hosted, proxies, nil)
+}
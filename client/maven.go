@@ -0,0 +1,48 @@
+package client
+
+// AddMavenRepos creates the configured maven hosted, proxy and group repos.
+func (r *ClientConfig) AddMavenRepos(config *NexusConfig) error {
+	var hosted []hostedRepo
+	for _, repoReq := range config.MavenRepos.Hosted {
+		hosted = append(hosted, newMavenHostedRepo(repoReq))
+	}
+	var proxies []proxyRepo
+	for _, repoReq := range config.MavenRepos.Proxy {
+		proxies = append(proxies, newMavenProxyRepo(repoReq))
+	}
+	var groups []groupRepo
+	for _, repoReq := range config.MavenRepos.Group {
+		groups = append(groups, newGroupRepo(repoReq))
+	}
+	return r.addRepos("maven", hosted, proxies, groups)
+}
+
+func newMavenAttributes(versionPolicy string, layoutPolicy string) *maven {
+	attributes := &maven{
+		VersionPolicy: versionPolicy,
+		LayoutPolicy:  layoutPolicy,
+	}
+	if len(attributes.VersionPolicy) == 0 {
+		attributes.VersionPolicy = "RELEASE"
+	}
+	if len(attributes.LayoutPolicy) == 0 {
+		attributes.LayoutPolicy = "STRICT"
+	}
+	return attributes
+}
+
+func newMavenHostedRepo(c MavenHostedRepo) hostedRepo {
+	repo := newHostedRepo(c.HostedRepo)
+	repo.Maven = newMavenAttributes(c.VersionPolicy, c.LayoutPolicy)
+	// Snapshots are redeployed with the same version
+	if repo.Maven.VersionPolicy == "SNAPSHOT" && len(c.WritePolicy) == 0 {
+		repo.Storage.WritePolicy = "allow"
+	}
+	return repo
+}
+
+func newMavenProxyRepo(c MavenProxyRepo) proxyRepo {
+	repo := newProxyRepo(c.ProxyRepo)
+	repo.Maven = newMavenAttributes(c.VersionPolicy, c.LayoutPolicy)
+	return repo
+}
@@ -0,0 +1,27 @@
+package client
+
+// AddNpmRepos creates the configured npm hosted, proxy and group repos.
+func (r *ClientConfig) AddNpmRepos(config *NexusConfig) error {
+	var hosted []hostedRepo
+	for _, repoReq := range config.NpmRepos.Hosted {
+		hosted = append(hosted, newHostedRepo(repoReq))
+	}
+	var proxies []proxyRepo
+	for _, repoReq := range config.NpmRepos.Proxy {
+		proxies = append(proxies, newNpmProxyRepo(repoReq))
+	}
+	var groups []groupRepo
+	for _, repoReq := range config.NpmRepos.Group {
+		groups = append(groups, newGroupRepo(repoReq))
+	}
+	return r.addRepos("npm", hosted, proxies, groups)
+}
+
+func newNpmProxyRepo(c NpmProxyRepo) proxyRepo {
+	repo := newProxyRepo(c.ProxyRepo)
+	repo.Npm = &npm{
+		RemoveNonCataloged: c.RemoveNonCataloged,
+		RemoveQuarantined:  c.RemoveQuarantined,
+	}
+	return repo
+}
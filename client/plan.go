@@ -0,0 +1,97 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The actions of a PlanEntry
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionNoop   = "noop"
+	ActionDelete = "delete"
+	// Saved on every apply, as nexus doesn't return enough of the resource to compare it
+	ActionUnknown = "unknown"
+)
+
+// PlanEntry is the action taken on a resource, or the action that would be taken in dry run mode.
+type PlanEntry struct {
+	Kind    string        `json:"kind"`
+	Name    string        `json:"name"`
+	Action  string        `json:"action"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+type Plan []PlanEntry
+
+// Plan returns the actions taken so far.
+func (r *ClientConfig) Plan() Plan {
+	return r.plan
+}
+
+func (r *ClientConfig) record(kind string, name string, action string, changes []FieldChange) {
+	r.plan = append(r.plan, PlanEntry{Kind: kind, Name: name, Action: action, Changes: changes})
+}
+
+// Text renders the plan one resource per line followed by its changed fields.
+func (p Plan) Text() string {
+	var text strings.Builder
+	count := map[string]int{}
+	for _, entry := range p {
+		count[entry.Action]++
+		symbol := " "
+		switch entry.Action {
+		case ActionCreate:
+			symbol = "+"
+		case ActionUpdate:
+			symbol = "~"
+		case ActionDelete:
+			symbol = "-"
+		case ActionUnknown:
+			symbol = "?"
+		}
+		text.WriteString(fmt.Sprintf("%s %-7s %s %s\n", symbol, entry.Action, entry.Kind, entry.Name))
+		for _, change := range entry.Changes {
+			text.WriteString(fmt.Sprintf("      %s\n", change))
+		}
+	}
+	text.WriteString(fmt.Sprintf("Plan: %d to create, %d to update, %d to delete, %d unchanged, %d unknown\n",
+		count[ActionCreate], count[ActionUpdate], count[ActionDelete], count[ActionNoop], count[ActionUnknown]))
+	return text.String()
+}
+
+// Drift returns the entries that are not in the state of the config or can't be compared.
+func (p Plan) Drift() Plan {
+	var drift Plan
+	for _, entry := range p {
+		if entry.Action != ActionNoop {
+			drift = append(drift, entry)
+		}
+	}
+	return drift
+}
+
+// DiffText renders the fields that differ between nexus and the config.
+func (p Plan) DiffText() string {
+	var text strings.Builder
+	for _, entry := range p.Drift() {
+		switch entry.Action {
+		case ActionCreate:
+			text.WriteString(fmt.Sprintf("+ %s %s: missing in nexus\n", entry.Kind, entry.Name))
+		case ActionDelete:
+			text.WriteString(fmt.Sprintf("- %s %s: not in config\n", entry.Kind, entry.Name))
+		case ActionUnknown:
+			text.WriteString(fmt.Sprintf("? %s %s: can't be compared, saved on every apply\n", entry.Kind, entry.Name))
+		default:
+			text.WriteString(fmt.Sprintf("~ %s %s\n", entry.Kind, entry.Name))
+		}
+		for _, change := range entry.Changes {
+			text.WriteString(fmt.Sprintf("    %s\n", change))
+		}
+	}
+	if text.Len() == 0 {
+		return "No drift\n"
+	}
+	return text.String()
+}
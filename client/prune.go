@@ -0,0 +1,177 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wesovilabs/koazee"
+)
+
+// Without these realms nobody can log in anymore.
+var alwaysProtectedRealms = []string{"NexusAuthenticatingRealm", "NexusAuthorizingRealm"}
+
+func (p Prune) isProtected(name string) bool {
+	contains, _ := koazee.StreamOf(p.Protected).Contains(name)
+	return contains
+}
+
+// pruneRealms returns the realms to activate if active is not what is requested,
+// keeping the protected ones. Returns nil if nothing changes.
+func (r *ClientConfig) pruneRealms(active []string, requested []string) []string {
+	requestedStream := koazee.StreamOf(requested)
+	alwaysProtected := koazee.StreamOf(alwaysProtectedRealms)
+	var realms []string
+	for _, realm := range active {
+		isRequested, _ := requestedStream.Contains(realm)
+		isAlwaysProtected, _ := alwaysProtected.Contains(realm)
+		if isRequested || isAlwaysProtected || r.Prune.isProtected(realm) {
+			realms = append(realms, realm)
+		} else {
+			logger.Info(fmt.Sprintf("Pruning realm %s", realm))
+		}
+	}
+	for _, realm := range requested {
+		contains, _ := koazee.StreamOf(realms).Contains(realm)
+		if !contains {
+			realms = append(realms, realm)
+		}
+	}
+	if len(realms) == len(active) {
+		changed := false
+		for i := range realms {
+			changed = changed || realms[i] != active[i]
+		}
+		if !changed {
+			return nil
+		}
+	}
+	return realms
+}
+
+type repositorySettings struct {
+	Name    string `json:"name"`
+	Format  string `json:"format"`
+	Type    string `json:"type"`
+	Storage struct {
+		BlobStoreName string `json:"blobStoreName"`
+	} `json:"storage"`
+}
+
+type blobStoreStatus struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	BlobCount int64  `json:"blobCount"`
+}
+
+// PruneRepositories deletes the repositories that are neither created or updated before nor protected.
+// Groups are deleted before the repos they may contain.
+func (r *ClientConfig) PruneRepositories() error {
+	var repos []repositorySettings
+	err := r.getJson("repositorySettings", &repos)
+	if err != nil {
+		return err
+	}
+	for _, repoType := range []string{"group", "proxy", "hosted"} {
+		for _, repo := range repos {
+			if repo.Type != repoType {
+				continue
+			}
+			resource := fmt.Sprintf("%s/%s/%s", repo.Format, repo.Type, repo.Name)
+			if r.plannedRepo(repo.Name, "") || r.Prune.isProtected(repo.Name) {
+				continue
+			}
+			r.record("repository", resource, ActionDelete, nil)
+			if r.DryRun {
+				continue
+			}
+			err := r.delete(fmt.Sprintf("repositories/%s", repo.Name))
+			if err != nil {
+				return err
+			}
+			logger.Info(fmt.Sprintf("Repo %s deleted", resource))
+		}
+	}
+	return nil
+}
+
+// PruneBlobStores deletes the blob stores that are neither created before nor protected nor used by any repository.
+// Blob stores containing blobs are only deleted if forced.
+func (r *ClientConfig) PruneBlobStores() error {
+	var repos []repositorySettings
+	err := r.getJson("repositorySettings", &repos)
+	if err != nil {
+		return err
+	}
+	var used []string
+	for _, repo := range repos {
+		// In dry run mode the pruned repos are still there
+		if !r.plannedRepo(repo.Name, ActionDelete) {
+			used = append(used, repo.Storage.BlobStoreName)
+		}
+	}
+
+	var stores []blobStoreStatus
+	err = r.getJson("blobstores", &stores)
+	if err != nil {
+		return err
+	}
+	// Nexus can't delete members of a group
+	for _, store := range stores {
+		if !strings.EqualFold(store.Type, "group") {
+			continue
+		}
+		var group map[string]interface{}
+		err := r.getJson(fmt.Sprintf("blobstores/group/%s", store.Name), &group)
+		if err != nil {
+			return err
+		}
+		used = append(used, blobStoreMembers(group)...)
+	}
+	usedStream := koazee.StreamOf(used)
+	for _, store := range stores {
+		inUse, _ := usedStream.Contains(store.Name)
+		if r.planned("blobstore", store.Name) || r.Prune.isProtected(store.Name) || inUse {
+			continue
+		}
+		if store.BlobCount > 0 && !r.Prune.Force {
+			logger.Warn(fmt.Sprintf("Blobstore %s contains %d blobs. Not pruned without force", store.Name, store.BlobCount))
+			continue
+		}
+		r.record("blobstore", store.Name, ActionDelete, nil)
+		if r.DryRun {
+			continue
+		}
+		err := r.delete(fmt.Sprintf("blobstores/%s", store.Name))
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Blobstore %s deleted", store.Name))
+	}
+	return nil
+}
+
+// planned reports whether the resource was handled before.
+func (r *ClientConfig) planned(kind string, name string) bool {
+	for _, entry := range r.plan {
+		if entry.Kind == kind && entry.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// plannedRepo reports whether the repository was handled before with action, or any action if empty.
+// Repository names are unique over all formats. Nexus reports some formats
+// different from the api path, e.g. maven2, so only the name is compared.
+func (r *ClientConfig) plannedRepo(name string, action string) bool {
+	for _, entry := range r.plan {
+		if entry.Kind != "repository" || (len(action) > 0 && entry.Action != action) {
+			continue
+		}
+		parts := strings.Split(entry.Name, "/")
+		if strings.EqualFold(parts[len(parts)-1], name) {
+			return true
+		}
+	}
+	return false
+}
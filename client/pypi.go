@@ -0,0 +1,26 @@
+package client
+
+// AddPypiRepos creates the configured pypi hosted, proxy and group repos.
+func (r *ClientConfig) AddPypiRepos(config *NexusConfig) error {
+	var hosted []hostedRepo
+	for _, repoReq := range config.PypiRepos.Hosted {
+		hosted = append(hosted, newHostedRepo(repoReq))
+	}
+	var proxies []proxyRepo
+	for _, repoReq := range config.PypiRepos.Proxy {
+		proxies = append(proxies, newPypiProxyRepo(repoReq))
+	}
+	var groups []groupRepo
+	for _, repoReq := range config.PypiRepos.Group {
+		groups = append(groups, newGroupRepo(repoReq))
+	}
+	return r.addRepos("pypi", hosted, proxies, groups)
+}
+
+func newPypiProxyRepo(c PypiProxyRepo) proxyRepo {
+	repo := newProxyRepo(c.ProxyRepo)
+	repo.Pypi = &pypi{
+		RemoveQuarantined: c.RemoveQuarantined,
+	}
+	return repo
+}
@@ -0,0 +1,55 @@
+package client
+
+// AddRawRepos creates the configured raw hosted, proxy and group repos.
+// The legacy rawRepo entry is created as additional hosted repo.
+func (r *ClientConfig) AddRawRepos(config *NexusConfig) error {
+	var hosted []hostedRepo
+	if len(config.RawRepo.Name) > 0 {
+		legacy := RawHostedRepo{
+			HostedRepo: HostedRepo{
+				Name:                        config.RawRepo.Name,
+				BlobStoreName:               config.RawRepo.Storage.BlobStoreName,
+				StrictContentTypeValidation: config.RawRepo.Storage.StrictContentTypeValidation,
+				WritePolicy:                 config.RawRepo.Storage.WritePolicy,
+			},
+		}
+		hosted = append(hosted, newRawHostedRepo(legacy))
+	}
+	for _, repoReq := range config.RawRepos.Hosted {
+		hosted = append(hosted, newRawHostedRepo(repoReq))
+	}
+	var proxies []proxyRepo
+	for _, repoReq := range config.RawRepos.Proxy {
+		proxies = append(proxies, newRawProxyRepo(repoReq))
+	}
+	var groups []groupRepo
+	for _, repoReq := range config.RawRepos.Group {
+		groups = append(groups, newRawGroupRepo(repoReq))
+	}
+	return r.addRepos("raw", hosted, proxies, groups)
+}
+
+func newRawAttributes(contentDisposition string) *raw {
+	if len(contentDisposition) == 0 {
+		contentDisposition = "ATTACHMENT"
+	}
+	return &raw{ContentDisposition: contentDisposition}
+}
+
+func newRawHostedRepo(c RawHostedRepo) hostedRepo {
+	repo := newHostedRepo(c.HostedRepo)
+	repo.Raw = newRawAttributes(c.ContentDisposition)
+	return repo
+}
+
+func newRawProxyRepo(c RawProxyRepo) proxyRepo {
+	repo := newProxyRepo(c.ProxyRepo)
+	repo.Raw = newRawAttributes(c.ContentDisposition)
+	return repo
+}
+
+func newRawGroupRepo(c RawGroupRepo) groupRepo {
+	repo := newGroupRepo(c.GroupRepo)
+	repo.Raw = newRawAttributes(c.ContentDisposition)
+	return repo
+}
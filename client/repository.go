@@ -0,0 +1,619 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/wesovilabs/koazee"
+)
+
+// The nexus repository payloads share the same layout for every format.
+// Only the format specific attribute blocks differ, so they are optional here.
+
+type storage struct {
+	BlobStoreName               string `json:"blobStoreName"`
+	StrictContentTypeValidation bool   `json:"strictContentTypeValidation"`
+	WritePolicy                 string `json:"writePolicy,omitempty"`
+}
+
+type cleanup struct {
+	PolicyNames []string `json:"policyNames"`
+}
+
+type component struct {
+	ProprietaryComponents bool `json:"proprietaryComponents"`
+}
+
+type proxy struct {
+	RemoteUrl      string `json:"remoteUrl"`
+	ContentMaxAge  int    `json:"contentMaxAge"`
+	MetadataMaxAge int    `json:"metadataMaxAge"`
+}
+
+type negativeCache struct {
+	Enabled    bool `json:"enabled"`
+	TimeToLive int  `json:"timeToLive"`
+}
+
+// Unset retries, timeout and user agent suffix keep the nexus defaults.
+type connection struct {
+	Retries                 *int   `json:"retries,omitempty"`
+	UserAgentSuffix         string `json:"userAgentSuffix,omitempty"`
+	Timeout                 *int   `json:"timeout,omitempty"`
+	EnableCircularRedirects bool   `json:"enableCircularRedirects"`
+	EnableCookies           bool   `json:"enableCookies"`
+	UseTrustStore           bool   `json:"useTrustStore"`
+}
+
+type httpClient struct {
+	Blocked        bool            `json:"blocked"`
+	AutoBlock      bool            `json:"autoBlock"`
+	Connection     *connection     `json:"connection,omitempty"`
+	Authentication *authentication `json:"authentication,omitempty"`
+}
+
+type group struct {
+	MemberNames    []string `json:"memberNames"`
+	WritableMember string   `json:"writableMember,omitempty"`
+}
+
+type maven struct {
+	VersionPolicy      string `json:"versionPolicy"`
+	LayoutPolicy       string `json:"layoutPolicy"`
+	ContentDisposition string `json:"contentDisposition,omitempty"`
+}
+
+type npm struct {
+	RemoveNonCataloged bool `json:"removeNonCataloged"`
+	RemoveQuarantined  bool `json:"removeQuarantined"`
+}
+
+type pypi struct {
+	RemoveQuarantined bool `json:"removeQuarantined"`
+}
+
+type apt struct {
+	Distribution string `json:"distribution"`
+	// Only proxies know flat repositories
+	Flat *bool `json:"flat,omitempty"`
+}
+
+type aptSigning struct {
+	Keypair    string `json:"keypair"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+type yum struct {
+	RepodataDepth int    `json:"repodataDepth"`
+	DeployPolicy  string `json:"deployPolicy,omitempty"`
+}
+
+type yumSigning struct {
+	Keypair    string `json:"keypair"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+type raw struct {
+	// ATTACHMENT or INLINE
+	ContentDisposition string `json:"contentDisposition"`
+}
+
+type hostedRepo struct {
+	Name       string      `json:"name"`
+	Online     bool        `json:"online"`
+	Storage    storage     `json:"storage"`
+	Cleanup    *cleanup    `json:"cleanup,omitempty"`
+	Component  *component  `json:"component,omitempty"`
+	Maven      *maven      `json:"maven,omitempty"`
+	Apt        *apt        `json:"apt,omitempty"`
+	AptSigning *aptSigning `json:"aptSigning,omitempty"`
+	Yum        *yum        `json:"yum,omitempty"`
+	Raw        *raw        `json:"raw,omitempty"`
+}
+
+type proxyRepo struct {
+	Name            string        `json:"name"`
+	Online          bool          `json:"online"`
+	Storage         storage       `json:"storage"`
+	Cleanup         *cleanup      `json:"cleanup,omitempty"`
+	Proxy           proxy         `json:"proxy"`
+	NegativeCache   negativeCache `json:"negativeCache"`
+	HttpClient      httpClient    `json:"httpClient"`
+	RoutingRuleName *string       `json:"routingRuleName,omitempty"`
+	Maven           *maven        `json:"maven,omitempty"`
+	Npm             *npm          `json:"npm,omitempty"`
+	Pypi            *pypi         `json:"pypi,omitempty"`
+	Apt             *apt          `json:"apt,omitempty"`
+	YumSigning      *yumSigning   `json:"yumSigning,omitempty"`
+	Raw             *raw          `json:"raw,omitempty"`
+}
+
+type groupRepo struct {
+	Name       string      `json:"name"`
+	Online     bool        `json:"online"`
+	Storage    storage     `json:"storage"`
+	Group      group       `json:"group"`
+	Maven      *maven      `json:"maven,omitempty"`
+	YumSigning *yumSigning `json:"yumSigning,omitempty"`
+	Raw        *raw        `json:"raw,omitempty"`
+}
+
+func newHostedRepo(c HostedRepo) hostedRepo {
+	repo := hostedRepo{
+		Name:   c.Name,
+		Online: true,
+		Storage: storage{
+			BlobStoreName:               c.BlobStoreName,
+			StrictContentTypeValidation: c.StrictContentTypeValidation,
+			WritePolicy:                 c.WritePolicy,
+		},
+	}
+	if len(repo.Storage.BlobStoreName) == 0 {
+		repo.Storage.BlobStoreName = "default"
+	}
+	if len(repo.Storage.WritePolicy) == 0 {
+		repo.Storage.WritePolicy = "allow_once"
+	}
+	repo.Cleanup = newCleanup(c.CleanupPolicies)
+	return repo
+}
+
+func newProxyRepo(c ProxyRepo) proxyRepo {
+	repo := proxyRepo{
+		Name:   c.Name,
+		Online: true,
+		Storage: storage{
+			BlobStoreName:               c.BlobStoreName,
+			StrictContentTypeValidation: c.StrictContentTypeValidation,
+		},
+		Proxy: proxy{
+			RemoteUrl:      c.Url,
+			ContentMaxAge:  1440,
+			MetadataMaxAge: 1440,
+		},
+		NegativeCache: negativeCache{Enabled: true, TimeToLive: 1440}, // The default 24h
+		HttpClient:    httpClient{Blocked: false, AutoBlock: true},
+	}
+	if len(repo.Storage.BlobStoreName) == 0 {
+		repo.Storage.BlobStoreName = "default"
+	}
+	repo.Cleanup = newCleanup(c.CleanupPolicies)
+	repo.RoutingRuleName = newRoutingRuleName(c.RoutingRule)
+	if c.ContentMaxAge != nil {
+		repo.Proxy.ContentMaxAge = *c.ContentMaxAge
+	}
+	if c.MetadataMaxAge != nil {
+		repo.Proxy.MetadataMaxAge = *c.MetadataMaxAge
+	}
+	if c.NegativeCache != nil {
+		repo.NegativeCache = negativeCache{Enabled: c.NegativeCache.Enabled, TimeToLive: c.NegativeCache.TimeToLive}
+	}
+	if len(c.BearerToken) > 0 {
+		repo.HttpClient.Authentication = &authentication{BearerToken: c.BearerToken, Type: "bearerToken"}
+	} else if len(c.Username) > 0 {
+		repo.HttpClient.Authentication = &authentication{Username: c.Username, Password: c.Password, Type: "username"}
+	}
+	return repo
+}
+
+// newConnection returns nil without settings, so the ones of existing repos are kept.
+// Unset settings keep their live values as well.
+func newConnection(c *Connection) *connection {
+	if c == nil {
+		return nil
+	}
+	return &connection{
+		Retries:                 c.Retries,
+		UserAgentSuffix:         c.UserAgentSuffix,
+		Timeout:                 c.Timeout,
+		EnableCircularRedirects: c.EnableCircularRedirects,
+		EnableCookies:           c.EnableCookies,
+		UseTrustStore:           c.UseTrustStore,
+	}
+}
+
+// newCleanup returns nil if the policies are unset, so the ones of existing repos are kept.
+// An empty list removes them.
+func newCleanup(policyNames []string) *cleanup {
+	if policyNames == nil {
+		return nil
+	}
+	return &cleanup{PolicyNames: policyNames}
+}
+
+func newGroupRepo(c GroupRepo) groupRepo {
+	repo := groupRepo{
+		Name:   c.Name,
+		Online: true,
+		Storage: storage{
+			BlobStoreName:               c.BlobStoreName,
+			StrictContentTypeValidation: true,
+		},
+		Group: group{
+			MemberNames: c.Members,
+		},
+	}
+	if len(repo.Storage.BlobStoreName) == 0 {
+		repo.Storage.BlobStoreName = "default"
+	}
+	return repo
+}
+
+// addRepos creates or updates the hosted and proxy repos of format first and then the groups,
+// because nexus rejects groups with unknown members.
+func (r *ClientConfig) addRepos(format string, hosted []hostedRepo, proxies []proxyRepo, groups []groupRepo) error {
+	for _, repoReq := range hosted {
+		_, err := r.createOrUpdateRepo(format, "hosted", repoReq.Name, repoReq)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Repo %s hosted is there %s", format, repoReq.Name))
+	}
+
+	for _, repoReq := range proxies {
+		_, err := r.createOrUpdateRepo(format, "proxy", repoReq.Name, repoReq)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Repo %s proxy is there %s", format, repoReq.Name))
+	}
+
+	for _, repoReq := range groups {
+		_, err := r.createOrUpdateRepo(format, "group", repoReq.Name, repoReq)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Repo %s group is there %s", format, repoReq.Name))
+	}
+	return nil
+}
+
+// newRequest builds an authenticated json request against the nexus rest api.
+// body is marshalled to json if present.
+func (r *ClientConfig) newRequest(method string, path string, body interface{}) (*http.Request, error) {
+	// plan and diff only report, they never write
+	if r.DryRun && method != "GET" {
+		return nil, fmt.Errorf("%s %s is not allowed in dry run", method, path)
+	}
+	var content io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		content = bytes.NewBuffer(b)
+	}
+	request, err := http.NewRequest(method, r.baseUrl()+path, content)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("accept", "application/json")
+	request.SetBasicAuth("admin", r.Password)
+	return request, nil
+}
+
+// getJson reads path into out.
+func (r *ClientConfig) getJson(path string, out interface{}) error {
+	request, err := r.newRequest("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	switch status := response.StatusCode; status {
+	case http.StatusOK:
+		content, err := io.ReadAll(response.Body)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(content, out)
+	default:
+		return NexusError{
+			message:    fmt.Sprintf("Can't read %s", path),
+			statuscode: status,
+		}
+	}
+}
+
+func (r *ClientConfig) delete(path string) error {
+	request, err := r.newRequest("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	switch status := response.StatusCode; status {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	default:
+		return NexusError{
+			message:    fmt.Sprintf("Can't delete %s", path),
+			statuscode: status,
+		}
+	}
+}
+
+// createOrUpdateRepo creates the repository repositories/{format}/{type}/{name} from repo.
+// An existing repository is updated if it differs from repo.
+// Members of existing groups missing in repo are kept. Otherwise nexus would drop them.
+// Settings repo leaves unset keep their live values.
+// Returns the repository as it is in nexus afterwards.
+func (r *ClientConfig) createOrUpdateRepo(format string, repoType string, name string, repo interface{}) (map[string]interface{}, error) {
+	desired, err := toMap(repo)
+	if err != nil {
+		return nil, err
+	}
+	if repoType == "group" {
+		err = r.validateGroupMembers(format, name, groupMembers(desired))
+		if err != nil {
+			return nil, err
+		}
+	}
+	live, err := r.getRepo(format, repoType, name)
+	if err != nil {
+		return nil, err
+	}
+	resource := fmt.Sprintf("%s/%s/%s", format, repoType, name)
+	if live == nil {
+		r.record("repository", resource, ActionCreate, nil)
+		if r.DryRun {
+			return desired, nil
+		}
+		err = r.createRepo(format, repoType, name, desired)
+		if err != nil {
+			return nil, err
+		}
+		live, err = r.getRepo(format, repoType, name)
+		if err != nil {
+			return nil, err
+		}
+		if live == nil {
+			return nil, NexusError{
+				message:    fmt.Sprintf("Can't create %s %s repo %s", format, repoType, name),
+				statuscode: http.StatusNotFound,
+			}
+		}
+		return live, nil
+	}
+
+	if repoType == "group" {
+		r.mergeGroupMembers(desired, live)
+	}
+	changes := append(diffFields("", desired, live), clearedFields(clearableRepoFields, desired, live)...)
+	changes = r.withSecretChanges(resource, changes, desired)
+	if len(changes) == 0 {
+		r.record("repository", resource, ActionNoop, nil)
+		return live, nil
+	}
+	r.record("repository", resource, ActionUpdate, changes)
+	for _, change := range changes {
+		logger.Info(fmt.Sprintf("Repo %s %s %s differs in %s", format, repoType, name, change))
+	}
+	if r.DryRun {
+		mergeMaps(live, desired)
+		return live, nil
+	}
+	// Nexus replaces the whole repository, so what the config leaves out comes from live
+	body, err := toMap(live)
+	if err != nil {
+		return nil, err
+	}
+	mergeMaps(body, desired)
+	for _, key := range readOnlyRepoFields {
+		delete(body, key)
+	}
+	err = r.updateRepo(format, repoType, name, body)
+	if err != nil {
+		return nil, err
+	}
+	mergeMaps(live, desired)
+	return live, nil
+}
+
+// Fields nexus reports for a repository but does not accept on update.
+var readOnlyRepoFields = []string{"format", "type", "url"}
+
+// Fields the config removes by leaving them unset, e.g. docker connectors.
+var clearableRepoFields = []string{"docker.httpsPort", "docker.subdomain"}
+
+// getRepo reads the repository repositories/{format}/{type}/{name}.
+// Returns nil if there is no such repository.
+func (r *ClientConfig) getRepo(format string, repoType string, name string) (map[string]interface{}, error) {
+	request, err := r.newRequest("GET", fmt.Sprintf("repositories/%s/%s/%s", format, repoType, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	// Close request body anyway
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	switch status := response.StatusCode; status {
+	case http.StatusOK:
+		{
+			content, err := io.ReadAll(response.Body)
+			if err != nil {
+				return nil, err
+			}
+			var repo map[string]interface{}
+			err = json.Unmarshal(content, &repo)
+			if err != nil {
+				return nil, err
+			}
+			return repo, nil
+		}
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, NexusError{
+			message:    "Unknown error",
+			statuscode: status,
+		}
+	}
+}
+
+// createRepo creates the repository repositories/{format}/{type}/{name} from repo.
+func (r *ClientConfig) createRepo(format string, repoType string, name string, repo interface{}) error {
+	request, err := r.newRequest("POST", fmt.Sprintf("repositories/%s/%s", format, repoType), repo)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	switch status := response.StatusCode; status {
+	case http.StatusCreated:
+		logger.Info(fmt.Sprintf("Repo %s %s %s created", format, repoType, name))
+	default:
+		return NexusError{
+			message:    "Unknown error",
+			statuscode: status,
+		}
+	}
+	return nil
+}
+
+// updateRepo replaces the repository repositories/{format}/{type}/{name} with repo.
+func (r *ClientConfig) updateRepo(format string, repoType string, name string, repo interface{}) error {
+	request, err := r.newRequest("PUT", fmt.Sprintf("repositories/%s/%s/%s", format, repoType, name), repo)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	switch status := response.StatusCode; status {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		logger.Info(fmt.Sprintf("Repo %s %s %s updated", format, repoType, name))
+	default:
+		return NexusError{
+			message:    "Unknown error",
+			statuscode: status,
+		}
+	}
+	return nil
+}
+
+// mergeGroupMembers sets the members of the desired group in the declared order.
+// Live members that are not declared follow, unless dropped in prune mode.
+func (r *ClientConfig) mergeGroupMembers(desired map[string]interface{}, live map[string]interface{}) {
+	members := r.mergeMembers(groupMembers(live), groupMembers(desired))
+	desiredGroup, ok := desired["group"].(map[string]interface{})
+	if !ok {
+		desiredGroup = map[string]interface{}{}
+		desired["group"] = desiredGroup
+	}
+	memberNames := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		memberNames = append(memberNames, member)
+	}
+	desiredGroup["memberNames"] = memberNames
+}
+
+// mergeMembers returns the desired members in their order followed by the live members
+// that are not desired. In prune mode those are dropped unless protected.
+func (r *ClientConfig) mergeMembers(live []string, desired []string) []string {
+	desiredMembers := koazee.StreamOf(desired)
+	members := append([]string{}, desired...)
+	for _, member := range live {
+		contains, _ := desiredMembers.Contains(member)
+		if !contains && (!r.Prune.Enabled || r.Prune.isProtected(member)) {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// validateGroupMembers checks that the members of the group are repositories of its format.
+// Members planned for creation count as existing.
+func (r *ClientConfig) validateGroupMembers(format string, name string, members []string) error {
+	repos, err := r.liveRepositories()
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		memberFormat, found := r.plannedRepoFormat(member)
+		for _, repo := range repos {
+			if strings.EqualFold(repo.Name, member) {
+				memberFormat, found = apiFormat(repo.Format), true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s group %s has the unknown member %s", format, name, member)
+		}
+		if memberFormat != format {
+			return fmt.Errorf("member %s of %s group %s is a %s repo", member, format, name, memberFormat)
+		}
+	}
+	return nil
+}
+
+// liveRepositories returns the repositories nexus had at the first call.
+// The ones created afterwards are in the plan.
+func (r *ClientConfig) liveRepositories() ([]repositorySummary, error) {
+	if r.repositories != nil {
+		return r.repositories, nil
+	}
+	repos := []repositorySummary{}
+	err := r.getJson("repositories", &repos)
+	if err != nil {
+		return nil, err
+	}
+	r.repositories = repos
+	return repos, nil
+}
+
+// plannedRepoFormat returns the format of the repository planned for creation.
+func (r *ClientConfig) plannedRepoFormat(name string) (string, bool) {
+	for _, entry := range r.plan {
+		if entry.Kind != "repository" || entry.Action != ActionCreate {
+			continue
+		}
+		parts := strings.Split(entry.Name, "/")
+		if len(parts) == 3 && strings.EqualFold(parts[2], name) {
+			return parts[0], true
+		}
+	}
+	return "", false
+}
+
+// groupMembers returns the member names of the group repo.
+func groupMembers(repo map[string]interface{}) []string {
+	var members []string
+	if g, ok := repo["group"].(map[string]interface{}); ok {
+		if names, ok := g["memberNames"].([]interface{}); ok {
+			for _, name := range names {
+				members = append(members, fmt.Sprint(name))
+			}
+		}
+	}
+	return members
+}
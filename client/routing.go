@@ -0,0 +1,138 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type routingRuleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Mode        string   `json:"mode"`
+	Matchers    []string `json:"matchers"`
+}
+
+func newRoutingRuleRequest(c RoutingRule) routingRuleRequest {
+	rule := routingRuleRequest{
+		Name:        c.Name,
+		Description: c.Description,
+		Mode:        strings.ToUpper(c.Mode),
+		Matchers:    c.Matchers,
+	}
+	if len(rule.Mode) == 0 {
+		rule.Mode = "BLOCK"
+	}
+	return rule
+}
+
+// newRoutingRuleName returns nil without rule, so the rule of existing repos is kept.
+func newRoutingRuleName(name string) *string {
+	if len(name) == 0 {
+		return nil
+	}
+	return &name
+}
+
+// AddRoutingRules creates the configured routing rules or updates them if they differ.
+// Call it before creating the proxies referencing the rules.
+func (r *ClientConfig) AddRoutingRules(config *NexusConfig) error {
+	for _, ruleReq := range config.RoutingRules {
+		if len(ruleReq.Name) == 0 || len(ruleReq.Matchers) == 0 {
+			return fmt.Errorf("routing rule %q needs a name and matchers", ruleReq.Name)
+		}
+		rule := newRoutingRuleRequest(ruleReq)
+		if rule.Mode != "ALLOW" && rule.Mode != "BLOCK" {
+			return fmt.Errorf("routing rule %s has unknown mode %s. Use ALLOW or BLOCK", rule.Name, ruleReq.Mode)
+		}
+		err := r.createOrUpdateRoutingRule(rule)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ClientConfig) createOrUpdateRoutingRule(rule routingRuleRequest) error {
+	path := fmt.Sprintf("routing-rules/%s", rule.Name)
+	request, err := r.newRequest("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+
+	switch status := response.StatusCode; status {
+	case http.StatusNotFound:
+		r.record("routing-rule", rule.Name, ActionCreate, nil)
+		if r.DryRun {
+			return nil
+		}
+		err := r.sendRoutingRule("POST", "routing-rules", rule)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Routing rule %s created", rule.Name))
+		return nil
+	case http.StatusOK:
+	default:
+		return NexusError{
+			message:    "Unknown error",
+			statuscode: status,
+		}
+	}
+
+	var live map[string]interface{}
+	err = r.getJson(path, &live)
+	if err != nil {
+		return err
+	}
+	desired, err := toMap(rule)
+	if err != nil {
+		return err
+	}
+	changes := diffFields("", desired, live)
+	if len(changes) == 0 {
+		r.record("routing-rule", rule.Name, ActionNoop, nil)
+		return nil
+	}
+	r.record("routing-rule", rule.Name, ActionUpdate, changes)
+	for _, change := range changes {
+		logger.Info(fmt.Sprintf("Routing rule %s differs in %s", rule.Name, change))
+	}
+	if r.DryRun {
+		return nil
+	}
+	err = r.sendRoutingRule("PUT", path, rule)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Routing rule %s updated", rule.Name))
+	return nil
+}
+
+func (r *ClientConfig) sendRoutingRule(method string, path string, rule routingRuleRequest) error {
+	request, err := r.newRequest(method, path, rule)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	switch status := response.StatusCode; status {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return NexusError{
+			message:    fmt.Sprintf("Can't save routing rule %s", rule.Name),
+			statuscode: status,
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type taskFrequency struct {
+	Schedule string `json:"schedule"`
+	// Unix seconds
+	StartDate      int64  `json:"startDate,omitempty"`
+	TimeZoneOffset string `json:"timeZoneOffset,omitempty"`
+	RecurringDays  []int  `json:"recurringDays,omitempty"`
+	CronExpression string `json:"cronExpression,omitempty"`
+}
+
+type taskRequest struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Enabled    bool              `json:"enabled"`
+	AlertEmail string            `json:"alertEmail,omitempty"`
+	Frequency  taskFrequency     `json:"frequency"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type taskSummary struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// startDateLayouts are the accepted formats of the start date besides unix seconds.
+var startDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// parseStartDate returns the start date as unix seconds.
+// Dates without offset are in the timeZoneOffset, e.g. +02:00, or UTC.
+func parseStartDate(date string, timeZoneOffset string) (int64, error) {
+	if len(date) == 0 {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseInt(date, 10, 64)
+	if err == nil {
+		return seconds, nil
+	}
+	location := time.UTC
+	if len(timeZoneOffset) > 0 {
+		zone, err := time.Parse("-07:00", timeZoneOffset)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time zone offset %q. Use e.g. +02:00", timeZoneOffset)
+		}
+		location = zone.Location()
+	}
+	for _, layout := range startDateLayouts {
+		start, err := time.ParseInLocation(layout, date, location)
+		if err == nil {
+			return start.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid start date %q. Use e.g. 2024-01-01T02:00:00 or unix seconds", date)
+}
+
+func newTaskRequest(c Task) (taskRequest, error) {
+	startDate, err := parseStartDate(c.StartDate, c.TimeZoneOffset)
+	if err != nil {
+		return taskRequest{}, fmt.Errorf("task %s: %w", c.Name, err)
+	}
+	task := taskRequest{
+		Type:       c.Type,
+		Name:       c.Name,
+		Enabled:    c.Enabled == nil || *c.Enabled,
+		AlertEmail: c.AlertEmail,
+		Frequency: taskFrequency{
+			Schedule:       c.Schedule,
+			StartDate:      startDate,
+			TimeZoneOffset: c.TimeZoneOffset,
+			RecurringDays:  c.RecurringDays,
+			CronExpression: c.CronExpression,
+		},
+		Properties: map[string]string{},
+	}
+	if len(task.Frequency.Schedule) == 0 {
+		task.Frequency.Schedule = "manual"
+	}
+	if len(c.BlobStoreName) > 0 {
+		task.Properties["blobstoreName"] = c.BlobStoreName
+	}
+	if len(c.RepositoryName) > 0 {
+		task.Properties["repositoryName"] = c.RepositoryName
+	}
+	if c.Age > 0 {
+		task.Properties["age"] = strconv.Itoa(c.Age)
+	}
+	return task, nil
+}
+
+// AddTasks creates the configured tasks or updates them if they differ.
+// Tasks are matched by name.
+func (r *ClientConfig) AddTasks(config *NexusConfig) error {
+	if len(config.Tasks) == 0 {
+		return nil
+	}
+	// Validate all tasks before the first write
+	var requests []taskRequest
+	for _, taskReq := range config.Tasks {
+		if len(taskReq.Name) == 0 || len(taskReq.Type) == 0 {
+			return fmt.Errorf("task %q needs a name and a type", taskReq.Name)
+		}
+		task, err := newTaskRequest(taskReq)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, task)
+	}
+	var tasks struct {
+		Items []taskSummary `json:"items"`
+	}
+	err := r.getJson("tasks", &tasks)
+	if err != nil {
+		return err
+	}
+	for _, task := range requests {
+		var existing *taskSummary
+		for i := range tasks.Items {
+			if tasks.Items[i].Name == task.Name {
+				existing = &tasks.Items[i]
+				break
+			}
+		}
+		err := r.createOrUpdateTask(task, existing)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createOrUpdateTask creates the task or updates it if it differs.
+// Nexus doesn't return the schedule and properties of a task, so existing tasks are saved on every apply.
+func (r *ClientConfig) createOrUpdateTask(task taskRequest, existing *taskSummary) error {
+	if existing == nil {
+		r.record("task", task.Name, ActionCreate, nil)
+		if r.DryRun {
+			return nil
+		}
+		err := r.sendTask("POST", "tasks", task)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Task %s created", task.Name))
+		return nil
+	}
+
+	path := fmt.Sprintf("tasks/%s", existing.Id)
+	var live map[string]interface{}
+	err := r.getJson(path, &live)
+	if err != nil {
+		return err
+	}
+	desired, err := toMap(task)
+	if err != nil {
+		return err
+	}
+	changes := diffFields("", desired, live)
+	_, readable := live["frequency"]
+	switch {
+	case len(changes) > 0:
+		r.record("task", task.Name, ActionUpdate, changes)
+		for _, change := range changes {
+			logger.Info(fmt.Sprintf("Task %s differs in %s", task.Name, change))
+		}
+	case !readable:
+		r.record("task", task.Name, ActionUnknown, nil)
+		logger.Warn(fmt.Sprintf("Schedule and properties of task %s can't be read from nexus. They are saved on every apply", task.Name))
+	default:
+		r.record("task", task.Name, ActionNoop, nil)
+		return nil
+	}
+	if r.DryRun {
+		return nil
+	}
+	err = r.sendTask("PUT", path, task)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Task %s updated", task.Name))
+	return nil
+}
+
+func (r *ClientConfig) sendTask(method string, path string, task taskRequest) error {
+	request, err := r.newRequest(method, path, task)
+	if err != nil {
+		return err
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	switch status := response.StatusCode; status {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return NexusError{
+			message:    fmt.Sprintf("Can't save task %s", task.Name),
+			statuscode: status,
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+// AddYumRepos creates the configured yum hosted, proxy and group repos.
+func (r *ClientConfig) AddYumRepos(config *NexusConfig) error {
+	var hosted []hostedRepo
+	for _, repoReq := range config.YumRepos.Hosted {
+		hosted = append(hosted, newYumHostedRepo(repoReq))
+	}
+	var proxies []proxyRepo
+	for _, repoReq := range config.YumRepos.Proxy {
+		proxies = append(proxies, newYumProxyRepo(repoReq))
+	}
+	var groups []groupRepo
+	for _, repoReq := range config.YumRepos.Group {
+		groups = append(groups, newYumGroupRepo(repoReq))
+	}
+	return r.addRepos("yum", hosted, proxies, groups)
+}
+
+func newYumHostedRepo(c YumHostedRepo) hostedRepo {
+	repo := newHostedRepo(c.HostedRepo)
+	repo.Yum = &yum{
+		RepodataDepth: c.RepodataDepth,
+		DeployPolicy:  c.DeployPolicy,
+	}
+	if len(repo.Yum.DeployPolicy) == 0 {
+		repo.Yum.DeployPolicy = "STRICT"
+	}
+	return repo
+}
+
+func newYumProxyRepo(c YumProxyRepo) proxyRepo {
+	repo := newProxyRepo(c.ProxyRepo)
+	if len(c.Keypair) > 0 {
+		repo.YumSigning = &yumSigning{
+			Keypair:    c.Keypair,
+			Passphrase: c.Passphrase,
+		}
+	}
+	return repo
+}
+
+func newYumGroupRepo(c YumGroupRepo) groupRepo {
+	repo := newGroupRepo(c.GroupRepo)
+	if len(c.Keypair) > 0 {
+		repo.YumSigning = &yumSigning{
+			Keypair:    c.Keypair,
+			Passphrase: c.Passphrase,
+		}
+	}
+	return repo
+}
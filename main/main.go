@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 	"github.com/suikast42/nexus-initlzr/client"
@@ -14,6 +20,27 @@ import (
 var logger, _ = zap.NewProduction()
 
 func main() {
+	command, args := "apply", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "Only read from nexus and print the plan")
+	output := flags.String("output", "text", "Format of the plan or diff. text or json")
+	prune := flags.Bool("prune", false, "Remove repositories, group members, blob stores and realms not in the config")
+	force := flags.Bool("force", false, "Prune blob stores that still contain blobs")
+	file := flags.String("file", "", "File the export writes the config to. Defaults to stdout")
+	rotateSecrets := flags.Bool("rotate-secrets", false, "Save passwords and tokens on every run. Nexus never returns them, so changed ones are not detected otherwise")
+	_ = flags.Parse(args)
+	switch command {
+	case "apply":
+	case "plan", "diff", "export":
+		*dryRun = true
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %s. Use apply, plan, diff or export\n", command)
+		os.Exit(2)
+	}
+
 	viper.SetEnvPrefix("NEXUS")
 	viper.AutomaticEnv()
 	err := readConfig()
@@ -26,6 +53,9 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	restoreAttributeKeys(&nexusConfig)
+	nexusConfig.Prune.Enabled = nexusConfig.Prune.Enabled || *prune
+	nexusConfig.Prune.Force = nexusConfig.Prune.Force || *force
 
 	nexusClient := client.ClientConfig{
 		Address:  nexusConfig.Address,
@@ -39,6 +69,9 @@ func main() {
 				},
 			},
 		},
+		DryRun:        *dryRun,
+		RotateSecrets: *rotateSecrets,
+		Prune:         nexusConfig.Prune,
 	}
 	logger.Info(fmt.Sprintf("nexus.address: %s", nexusClient.Address))
 	logger.Info(fmt.Sprintf("nexus.port: %d", nexusClient.Port))
@@ -46,34 +79,239 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	err = nexusClient.ChangeAdmin123Password()
+	if command == "export" {
+		err = exportConfig(&nexusClient, *file)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+	err = apply(&nexusClient, &nexusConfig)
 	if err != nil {
 		panic(err)
 	}
-
-	for _, v := range nexusConfig.BlobStores {
-		err := nexusClient.AddBlobStore(v.Name, v.Capacity)
+	if command == "diff" {
+		drift := nexusClient.Plan().Drift()
+		err = printDiff(drift, *output)
+		if err != nil {
+			panic(err)
+		}
+		// Exit code 1 like diff does, so scheduled checks fail on drift.
+		// Resources that can't be compared don't fail them
+		for _, entry := range drift {
+			if entry.Action != client.ActionUnknown {
+				os.Exit(1)
+			}
+		}
+		return
+	}
+	if *dryRun {
+		err = printPlan(nexusClient.Plan(), *output)
 		if err != nil {
 			panic(err)
 		}
 	}
-	realms := []string{"DockerToken"}
+}
+
+// apply brings nexus in the state of the config.
+// In dry run mode the client only records the plan.
+func apply(nexusClient *client.ClientConfig, nexusConfig *client.NexusConfig) error {
+	err := nexusClient.ChangeAdmin123Password()
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddBlobStores(nexusConfig.BlobStores)
+	if err != nil {
+		return err
+	}
+	err = nexusClient.AddCleanupPolicies(nexusConfig)
+	if err != nil {
+		return err
+	}
+	err = nexusClient.AddRoutingRules(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	realms := nexusConfig.Realms
+	if len(realms) == 0 {
+		realms = []string{"DockerToken"}
+	}
 	err = nexusClient.ActivateRealm(realms)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	err = nexusClient.AddDockerRepos(&nexusConfig, nexusConfig.DockerGroup)
+	err = nexusClient.AddDockerRepos(nexusConfig, nexusConfig.DockerGroup)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	err = nexusClient.CreateRawRepo(&nexusConfig)
+	err = nexusClient.AddRawRepos(nexusConfig)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	err = nexusClient.AddMavenRepos(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddNpmRepos(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddPypiRepos(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddHelmRepos(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddGoRepos(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddAptRepos(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddYumRepos(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddRepositories(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddDockerPullGroups(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	err = nexusClient.AddTasks(nexusConfig)
+	if err != nil {
+		return err
+	}
+
+	if nexusConfig.Prune.Enabled {
+		err = nexusClient.PruneRepositories()
+		if err != nil {
+			return err
+		}
+		err = nexusClient.PruneBlobStores()
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
+func printDiff(drift client.Plan, output string) error {
+	switch output {
+	case "json":
+		if drift == nil {
+			drift = client.Plan{}
+		}
+		b, err := json.MarshalIndent(drift, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "text":
+		fmt.Print(drift.DiffText())
+	default:
+		return fmt.Errorf("unknown output %s. Use text or json", output)
+	}
+	return nil
+}
+
+// exportConfig writes the config of the running nexus to file or stdout.
+// Empty sections are left out.
+func exportConfig(nexusClient *client.ClientConfig, file string) error {
+	config, err := nexusClient.Export()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	var content map[string]interface{}
+	err = json.Unmarshal(b, &content)
+	if err != nil {
+		return err
+	}
+	compact(content)
+	b, err = json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+	if len(file) == 0 {
+		fmt.Println(string(b))
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Writing config to %s", file))
+	return os.WriteFile(file, append(b, '\n'), 0600)
+}
+
+// compact removes nulls, empty strings, empty lists and empty objects.
+func compact(content map[string]interface{}) {
+	for key, value := range content {
+		switch v := value.(type) {
+		case nil:
+			delete(content, key)
+		case string:
+			if len(v) == 0 {
+				delete(content, key)
+			}
+		case []interface{}:
+			for _, element := range v {
+				if m, ok := element.(map[string]interface{}); ok {
+					compact(m)
+				}
+			}
+			if len(v) == 0 {
+				delete(content, key)
+			}
+		case map[string]interface{}:
+			compact(v)
+			if len(v) == 0 {
+				delete(content, key)
+			}
+		}
+	}
+}
+
+func printPlan(plan client.Plan, output string) error {
+	switch output {
+	case "json":
+		b, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "text":
+		fmt.Print(plan.Text())
+	default:
+		return fmt.Errorf("unknown output %s. Use text or json", output)
+	}
+	return nil
+}
+
+// Original keys of the repository attributes. Viper lowercases all keys,
+// but nexus expects the attributes as they are.
+var attributeKeys = map[string]string{}
+
 func readConfig() error {
 	//wd, err := os.Getwd()
 	//if err != nil {
@@ -81,10 +319,9 @@ func readConfig() error {
 	//}
 	//fmt.Println("Current path:", wd)
 	viper.SetConfigType("json") // Look for specific type
-	{                           //initialize local cfg
-		viper.AddConfigPath("./")
-		viper.SetConfigName("config") // Register config file name (no extension)
-		err := viper.ReadInConfig()
+	configPaths := []string{"./"}
+	{ //initialize local cfg
+		err := readConfigFile("config", configPaths, viper.ReadConfig)
 		if err != nil {
 			return err
 		}
@@ -93,14 +330,14 @@ func readConfig() error {
 		//NEXUS_INIT_CONFIG_PATH=C:\IDE\Projects_Git\playground\nexus-initlzr\main\override_config.json
 		cfg, present := os.LookupEnv("NEXUS_INIT_CONFIG_PATH")
 		if present {
-			viper.AddConfigPath(cfg)
+			configPaths = append(configPaths, cfg)
 		}
 	}
 	{
 		cfg, present := os.LookupEnv("NEXUS_INIT_CONFIG_FILE")
 		if present {
-			viper.SetConfigName(cfg) // Register config file name (no extension)
-			err := viper.MergeInConfig()
+			// Register config file name (no extension)
+			err := readConfigFile(cfg, configPaths, viper.MergeConfig)
 			if err != nil {
 				return err
 			}
@@ -108,3 +345,75 @@ func readConfig() error {
 	}
 	return nil
 }
+
+// readConfigFile passes the first config file name found in paths to read
+// and remembers the original keys of its repository attributes.
+func readConfigFile(name string, paths []string, read func(in io.Reader) error) error {
+	for _, path := range paths {
+		for _, file := range []string{filepath.Join(path, name+".json"), filepath.Join(path, name)} {
+			info, err := os.Stat(file)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			logger.Info(fmt.Sprintf("Reading config %s", file))
+			var config interface{}
+			err = json.Unmarshal(content, &config)
+			if err != nil {
+				return fmt.Errorf("config %s: %w", file, err)
+			}
+			collectAttributeKeys(config, false)
+			return read(bytes.NewReader(content))
+		}
+	}
+	return fmt.Errorf("config file %s not found in %s", name, paths)
+}
+
+// collectAttributeKeys remembers the keys below any attributes object.
+func collectAttributeKeys(value interface{}, inAttributes bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if inAttributes {
+				attributeKeys[strings.ToLower(key)] = key
+			}
+			collectAttributeKeys(child, inAttributes || strings.EqualFold(key, "attributes"))
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectAttributeKeys(child, inAttributes)
+		}
+	}
+}
+
+// restoreAttributeKeys gives the attributes of the repositories their original keys back.
+func restoreAttributeKeys(nexusConfig *client.NexusConfig) {
+	for i := range nexusConfig.Repositories {
+		if attributes := nexusConfig.Repositories[i].Attributes; attributes != nil {
+			nexusConfig.Repositories[i].Attributes = restoreKeys(attributes).(map[string]interface{})
+		}
+	}
+}
+
+func restoreKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		restored := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			if original, ok := attributeKeys[key]; ok {
+				key = original
+			}
+			restored[key] = restoreKeys(child)
+		}
+		return restored
+	case []interface{}:
+		for i, child := range v {
+			v[i] = restoreKeys(child)
+		}
+		return v
+	}
+	return value
+}